@@ -1,6 +1,10 @@
 package elasticsearch
 
 import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -11,6 +15,9 @@ type Cluster struct {
 	nodes        Nodes
 	pingInterval time.Duration
 	shutdown     chan chan bool
+	shutdownOnce sync.Once
+	done         chan struct{} // closed when loop returns
+	next         uint64        // for round-robin across nodes; see Nodes.candidates
 }
 
 // NewCluster returns a new, actively-managed Cluster, representing the
@@ -19,18 +26,20 @@ type Cluster struct {
 //
 // The Cluster will ping each Node on a schedule dictated by pingInterval.
 // Each node has pingTimeout to respond before the ping is marked as failed.
+// The options are applied to each Node.
 //
 // TODO node discovery from the list of seed-nodes.
-func NewCluster(endpoints []string, pingInterval, pingTimeout time.Duration) *Cluster {
+func NewCluster(endpoints []string, pingInterval, pingTimeout time.Duration, options ...Option) *Cluster {
 	nodes := Nodes{}
 	for _, endpoint := range endpoints {
-		nodes = append(nodes, NewNode(endpoint, pingTimeout))
+		nodes = append(nodes, NewNode(endpoint, pingTimeout, options...))
 	}
 
 	c := &Cluster{
 		nodes:        nodes,
 		pingInterval: pingInterval,
 		shutdown:     make(chan chan bool),
+		done:         make(chan struct{}),
 	}
 	go c.loop()
 	return c
@@ -40,13 +49,20 @@ func NewCluster(endpoints []string, pingInterval, pingTimeout time.Duration) *Cl
 // Nodes, and serves incoming requests. Because every request against the
 // cluster must pass through here, it cannot block.
 func (c *Cluster) loop() {
-	ticker := time.Tick(c.pingInterval)
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.pingInterval)
+	defer ticker.Stop()
+
+	var pings sync.WaitGroup
 	for {
 		select {
-		case <-ticker:
-			go c.nodes.pingAll()
+		case <-ticker.C:
+			pings.Add(1)
+			go func() { c.nodes.pingAll(); pings.Done() }()
 
 		case q := <-c.shutdown:
+			pings.Wait()
 			q <- true
 			return
 		}
@@ -67,6 +83,11 @@ func (c *Cluster) MultiSearch(r MultiSearchRequest) (response MultiSearchRespons
 	return
 }
 
+func (c *Cluster) Count(r CountRequest) (response CountResponse, err error) {
+	err = c.Execute(r, &response)
+	return
+}
+
 func (c *Cluster) Index(r IndexRequest) (response IndexResponse, err error) {
 	err = c.Execute(r, &response)
 	return
@@ -87,25 +108,113 @@ func (c *Cluster) Delete(r DeleteRequest) (response IndexResponse, err error) {
 	return
 }
 
+func (c *Cluster) Get(r GetRequest) (response GetResponse, err error) {
+	err = c.Execute(r, &response)
+	return
+}
+
+// Exists reports whether the document exists in the cluster.
+func (c *Cluster) Exists(r ExistsRequest) (bool, error) {
+	return exists(c.Do(r))
+}
+
+// IndexExists reports whether the index exists in the cluster.
+func (c *Cluster) IndexExists(r IndexExistsRequest) (bool, error) {
+	return exists(c.Do(r))
+}
+
+func (c *Cluster) MultiGet(r MultiGetRequest) (response MultiGetResponse, err error) {
+	err = c.Execute(r, &response)
+	return
+}
+
 func (c *Cluster) Bulk(r BulkRequest) (response BulkResponse, err error) {
 	err = c.Execute(r, &response)
 	return
 }
 
+func (c *Cluster) IndexStats(r IndexStatsRequest) (response IndexStatsResponse, err error) {
+	err = c.Execute(r, &response)
+	return
+}
+
 // Executes the request against a suitable node and decodes server's reply into
 // response.
 func (c *Cluster) Execute(f Fireable, response interface{}) error {
-	node, err := c.nodes.getBest()
+	_, err := c.Fire(f, response)
+	return err
+}
+
+// Fire executes the request against a suitable node, decodes the server's
+// reply into v, and returns a description of the HTTP response.
+//
+// Requests are spread round-robin across the healthiest nodes. A node which
+// can't be dialed is passed over for a while, and the request is retried
+// against the next node.
+func (c *Cluster) Fire(f Fireable, v interface{}) (*Response, error) {
+	return c.FireContext(context.Background(), f, v)
+}
+
+// FireContext is Fire, aborting the request if ctx is done first.
+func (c *Cluster) FireContext(ctx context.Context, f Fireable, v interface{}) (*Response, error) {
+	f = replayable(f) // the same body for each node
+	nodes, err := c.nodes.candidates(atomic.AddUint64(&c.next, 1) - 1)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return node.Execute(f, response)
+	for _, node := range nodes {
+		var response *Response
+		response, err = node.FireContext(ctx, f, v)
+		if !isDialError(err) {
+			return response, err
+		}
+		node.markDown()
+	}
+
+	return nil, err
+}
+
+// Do sends the request to a suitable node and returns the server's raw
+// reply, whose body the caller must close.
+func (c *Cluster) Do(f Fireable) (*http.Response, error) {
+	return c.DoContext(context.Background(), f)
 }
 
-// Shutdown terminates the Cluster's event dispatcher.
+// DoContext is Do, aborting the request if ctx is done first.
+func (c *Cluster) DoContext(ctx context.Context, f Fireable) (*http.Response, error) {
+	f = replayable(f) // the same body for each node
+	nodes, err := c.nodes.candidates(atomic.AddUint64(&c.next, 1) - 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range nodes {
+		var r *http.Response
+		r, err = node.DoContext(ctx, f)
+		if !isDialError(err) {
+			return r, err
+		}
+		node.markDown()
+	}
+
+	return nil, err
+}
+
+// Shutdown terminates the Cluster's event dispatcher, after waiting for any
+// in-flight pings to complete. It's safe to call more than once.
 func (c *Cluster) Shutdown() {
-	q := make(chan bool)
-	c.shutdown <- q
-	<-q
+	c.shutdownOnce.Do(func() {
+		q := make(chan bool)
+		c.shutdown <- q
+		<-q
+	})
+	<-c.done
+}
+
+// Close implements io.Closer for a Cluster. It's equivalent to Shutdown, and
+// likewise safe to call more than once.
+func (c *Cluster) Close() error {
+	c.Shutdown()
+	return nil
 }
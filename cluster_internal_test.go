@@ -0,0 +1,37 @@
+package elasticsearch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClusterCloseStopsLoop(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	c := NewCluster([]string{server.URL}, 10*time.Millisecond, time.Second)
+
+	for i := 0; i < 2; i++ {
+		closed := make(chan error)
+		go func() { closed <- c.Close() }()
+
+		select {
+		case err := <-closed:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Close %d: timed out", i+1)
+		}
+	}
+
+	select {
+	case <-c.done:
+	default:
+		t.Errorf("expected the event dispatcher to have returned")
+	}
+}
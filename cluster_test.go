@@ -67,7 +67,7 @@ func TestClusterIndex(t *testing.T) {
 		t.Error(response.Error)
 	}
 
-	if expected, got := 1, response.Version; expected != got {
+	if expected, got := int64(1), response.Version; expected != got {
 		t.Errorf("expected version to be %d; got %d", expected, got)
 	}
 }
@@ -97,7 +97,7 @@ func TestClusterCreate(t *testing.T) {
 		t.Error(response.Error)
 	}
 
-	if expected, got := 1, response.Version; expected != got {
+	if expected, got := int64(1), response.Version; expected != got {
 		t.Errorf("expected version to be %d; got %d", expected, got)
 	}
 }
@@ -112,13 +112,13 @@ func TestClusterUpdate(t *testing.T) {
 	defer deleteIndices(t, []string{"twitter"})
 
 	response, err := c.Update(es.UpdateRequest{
-		es.IndexParams{
+		Params: es.IndexParams{
 			Index:   "twitter",
 			Type:    "tweet",
 			Id:      "1",
 			Refresh: "true",
 		},
-		map[string]interface{}{
+		Source: map[string]interface{}{
 			"script": `ctx._source.text = "some text"`,
 		},
 	})
@@ -131,7 +131,7 @@ func TestClusterUpdate(t *testing.T) {
 		t.Error(response.Error)
 	}
 
-	if expected, got := 2, response.Version; expected != got {
+	if expected, got := int64(2), response.Version; expected != got {
 		t.Errorf("expected version to be %d; got %d", expected, got)
 	}
 }
@@ -162,7 +162,7 @@ func TestClusterDelete(t *testing.T) {
 		t.Error(response.Error)
 	}
 
-	if expected, got := 2, response.Version; expected != got {
+	if expected, got := int64(2), response.Version; expected != got {
 		t.Errorf("expected version to be %d; got %d", expected, got)
 	}
 }
@@ -177,8 +177,8 @@ func TestClusterBulk(t *testing.T) {
 	defer deleteIndices(t, []string{"twitter"})
 
 	response, err := c.Bulk(es.BulkRequest{
-		es.BulkParams{Refresh: "true"},
-		[]es.BulkIndexable{
+		Params: es.BulkParams{Refresh: "true"},
+		Requests: []es.BulkIndexable{
 			es.IndexRequest{
 				es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"},
 				map[string]interface{}{"name": "James"},
@@ -201,7 +201,7 @@ func TestClusterBulk(t *testing.T) {
 		t.Fatalf("expected 3 responses, got %d", len(response.Items))
 	}
 
-	if expected, got := 2, response.Items[0].Version; expected != got {
+	if expected, got := int64(2), response.Items[0].Version; expected != got {
 		t.Errorf("expected version of doc to be %d; got %d", expected, got)
 	}
 
@@ -209,7 +209,7 @@ func TestClusterBulk(t *testing.T) {
 		t.Errorf("expected delete op to return found = false")
 	}
 
-	if expected, got := 1, response.Items[2].Version; expected != got {
+	if expected, got := int64(1), response.Items[2].Version; expected != got {
 		t.Errorf("expected version of doc to be %d; got %d", expected, got)
 	}
 }
@@ -234,11 +234,11 @@ func TestSimpleTermQuery(t *testing.T) {
 	}))
 
 	request := es.SearchRequest{
-		es.SearchParams{
+		Params: es.SearchParams{
 			Indices: []string{"twitter"},
 			Types:   []string{"tweet"},
 		},
-		q,
+		Query: q,
 	}
 
 	response, err := c.Search(request)
@@ -288,25 +288,25 @@ func TestMultiSearch(t *testing.T) {
 	request := es.MultiSearchRequest{
 		Requests: []es.SearchRequest{
 			es.SearchRequest{
-				es.SearchParams{
+				Params: es.SearchParams{
 					Indices: []string{"index1"},
 					Types:   []string{"foo"},
 				},
-				q1,
+				Query: q1,
 			},
 			es.SearchRequest{
-				es.SearchParams{
+				Params: es.SearchParams{
 					Indices: []string{"index2"},
 					Types:   []string{"bar"},
 				},
-				q2,
+				Query: q2,
 			},
 			es.SearchRequest{
-				es.SearchParams{
+				Params: es.SearchParams{
 					Indices: []string{}, // "index1", "index2" is not supported (!)
 					Types:   []string{}, // "type1", "type2" is not supported (!)
 				},
-				q3,
+				Query: q3,
 			},
 		},
 	}
@@ -383,11 +383,11 @@ func TestConstantScoreNoScore(t *testing.T) {
 	}
 
 	request := es.SearchRequest{
-		es.SearchParams{
+		Params: es.SearchParams{
 			Indices: []string{"twitter"},
 			Types:   []string{"tweet"},
 		},
-		q,
+		Query: q,
 	}
 
 	response, err := c.Search(request)
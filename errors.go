@@ -0,0 +1,94 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ESError is the error returned by Do and Fire, and so Execute and the typed
+// methods of Cluster, for a response with an error status. Fire still decodes
+// the response body into the caller's value, where possible.
+type ESError struct {
+	Status int
+	Type   string // eg. version_conflict_engine_exception; not set by older versions
+	Reason string
+
+	// Response is the HTTP response, eg. for its headers. Its body has been
+	// read, but for errors from Do, it can be read again.
+	Response *http.Response
+}
+
+func (e *ESError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("elasticsearch: status %d: %s", e.Status, e.Reason)
+	}
+	return fmt.Sprintf("elasticsearch: status %d: %s: %s", e.Status, e.Type, e.Reason)
+}
+
+// parseError builds the ESError for a response with the given status and
+// body. Older versions of ElasticSearch report the error as a string, and
+// newer ones as an object with a type and reason.
+func parseError(status int, body []byte) *ESError {
+	e := &ESError{Status: status}
+
+	var v struct {
+		Error json.RawMessage `json:"error"`
+	}
+	json.Unmarshal(body, &v)
+	e.Type, e.Reason = errorCause(v.Error)
+
+	if e.Reason == "" {
+		e.Reason = http.StatusText(status)
+	}
+	return e
+}
+
+// errorCause returns the type and reason of an error, which is either a
+// string or an object, in a response body.
+func errorCause(raw json.RawMessage) (typ, reason string) {
+	if len(raw) == 0 {
+		return "", ""
+	}
+
+	if err := json.Unmarshal(raw, &reason); err == nil {
+		return "", reason
+	}
+
+	var cause struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(raw, &cause); err != nil {
+		return "", string(raw)
+	}
+	return cause.Type, cause.Reason
+}
+
+// IsVersionConflict reports whether err is an ESError for a version
+// conflict, eg. from an index with an out-of-date Version.
+func IsVersionConflict(err error) bool {
+	var e *ESError
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.Status == http.StatusConflict || e.Type == "version_conflict_engine_exception"
+}
+
+// IsNotFound reports whether err is an ESError for a missing document or
+// index, eg. from a GetRequest or DeleteRequest.
+func IsNotFound(err error) bool {
+	var e *ESError
+	return errors.As(err, &e) && e.Status == http.StatusNotFound
+}
+
+// IgnoreNotFound returns nil if err is a not found error, and err otherwise.
+// It makes deletes idempotent: the response to a delete of a missing document
+// is still decoded by Fire, with Found false, and the error can be ignored.
+func IgnoreNotFound(err error) error {
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,251 @@
+package elasticsearch_test
+
+import (
+	"encoding/json"
+	"fmt"
+	es "github.com/peterbourgon/elasticsearch"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestESError(t *testing.T) {
+	for _, tuple := range []struct {
+		status         int
+		body           string
+		expectedType   string
+		expectedReason string
+	}{
+		{
+			status:         409,
+			body:           `{"error":{"root_cause":[],"type":"version_conflict_engine_exception","reason":"[1]: version conflict"},"status":409}`,
+			expectedType:   "version_conflict_engine_exception",
+			expectedReason: "[1]: version conflict",
+		},
+		{
+			status:         409,
+			body:           `{"error":"VersionConflictEngineException[[twitter][0] [tweet][1]: version conflict, current [2], provided [1]]","status":409}`,
+			expectedType:   "",
+			expectedReason: "VersionConflictEngineException[[twitter][0] [tweet][1]: version conflict, current [2], provided [1]]",
+		},
+		{
+			status:         502,
+			body:           `<html>Bad Gateway</html>`,
+			expectedType:   "",
+			expectedReason: "Bad Gateway",
+		},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tuple.status)
+			w.Write([]byte(tuple.body))
+		}))
+
+		node := es.NewNode(server.URL, time.Second)
+		_, err := node.Fire(es.IndexRequest{
+			es.IndexParams{Index: "twitter", Type: "tweet", Id: "1", Version: "1", VersionType: "external"},
+			map[string]string{"user": "kimchy"},
+		}, &es.IndexResponse{})
+		server.Close()
+
+		e, ok := err.(*es.ESError)
+		if !ok {
+			t.Fatalf("%s: expected an ESError; got %v", tuple.body, err)
+		}
+
+		if expected, got := tuple.status, e.Status; expected != got {
+			t.Errorf("%s: expected status = %d; got %d", tuple.body, expected, got)
+		}
+
+		if expected, got := tuple.expectedType, e.Type; expected != got {
+			t.Errorf("%s: expected type = %q; got %q", tuple.body, expected, got)
+		}
+
+		if expected, got := tuple.expectedReason, e.Reason; expected != got {
+			t.Errorf("%s: expected reason = %q; got %q", tuple.body, expected, got)
+		}
+	}
+}
+
+func TestIsVersionConflict(t *testing.T) {
+	for _, tuple := range []struct {
+		err      error
+		expected bool
+	}{
+		{&es.ESError{Status: 409, Type: "version_conflict_engine_exception"}, true},
+		{&es.ESError{Status: 409}, true},
+		{fmt.Errorf("index: %w", &es.ESError{Status: 409}), true},
+		{&es.ESError{Status: 404, Type: "index_not_found_exception"}, false},
+		{fmt.Errorf("timeout"), false},
+		{nil, false},
+	} {
+		if expected, got := tuple.expected, es.IsVersionConflict(tuple.err); expected != got {
+			t.Errorf("%v: expected %v; got %v", tuple.err, expected, got)
+		}
+	}
+}
+
+func TestBulkItemResponseIsVersionConflict(t *testing.T) {
+	body := `{"took":3,"errors":true,"items":[
+		{"index":{"_index":"twitter","_type":"tweet","_id":"1","_version":2,"status":200}},
+		{"index":{"_index":"twitter","_type":"tweet","_id":"2","status":409,"error":{"type":"version_conflict_engine_exception","reason":"[2]: version conflict"}}},
+		{"create":{"_index":"twitter","_type":"tweet","_id":"3","status":409,"error":"DocumentAlreadyExistsException[[twitter][0] [tweet][3]: document already exists]"}}
+	]}`
+
+	var response es.BulkResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, expected := range []bool{false, true, true} {
+		if got := response.Items[i].IsVersionConflict(); expected != got {
+			t.Errorf("item %d: expected %v; got %v", i, expected, got)
+		}
+	}
+
+	if expected, got := "[2]: version conflict", response.Items[1].Error; expected != got {
+		t.Errorf("expected error = %q; got %q", expected, got)
+	}
+
+	if expected, got := "version_conflict_engine_exception", response.Items[1].ErrorType; expected != got {
+		t.Errorf("expected error type = %q; got %q", expected, got)
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/twitter/tweet/1":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"_index":"twitter","_type":"tweet","_id":"1","found":false}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error":{"type":"exception","reason":"boom"},"status":500}`))
+		}
+	}))
+	defer server.Close()
+
+	node := es.NewNode(server.URL, time.Second)
+
+	_, err := node.Fire(es.GetRequest{Index: "twitter", Type: "tweet", Id: "1"}, &es.GetResponse{})
+	if !es.IsNotFound(err) {
+		t.Errorf("expected a not found error; got %v", err)
+	}
+
+	_, err = node.Fire(es.GetRequest{Index: "twitter", Type: "tweet", Id: "2"}, &es.GetResponse{})
+	if err == nil || es.IsNotFound(err) {
+		t.Errorf("expected an error other than not found; got %v", err)
+	}
+
+	if es.IsNotFound(nil) {
+		t.Errorf("expected nil not to be a not found error")
+	}
+}
+
+func TestNodeDoError(t *testing.T) {
+	for _, tuple := range []struct {
+		status         int
+		body           string
+		expectedType   string
+		expectedReason string
+	}{
+		{
+			status:         404,
+			body:           `{"error":{"root_cause":[],"type":"index_not_found_exception","reason":"no such index [twitter]"},"status":404}`,
+			expectedType:   "index_not_found_exception",
+			expectedReason: "no such index [twitter]",
+		},
+		{
+			status:         400,
+			body:           `{"error":{"root_cause":[],"type":"mapper_parsing_exception","reason":"failed to parse field [age] of type [long]"},"status":400}`,
+			expectedType:   "mapper_parsing_exception",
+			expectedReason: "failed to parse field [age] of type [long]",
+		},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Elastic-Product", "Elasticsearch")
+			w.WriteHeader(tuple.status)
+			w.Write([]byte(tuple.body))
+		}))
+
+		r, err := es.NewNode(server.URL, time.Second).Do(es.IndexRequest{
+			es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"},
+			map[string]string{"age": "old"},
+		})
+		server.Close()
+
+		if r != nil {
+			t.Errorf("%d: expected no response; got %v", tuple.status, r)
+		}
+
+		e, ok := err.(*es.ESError)
+		if !ok {
+			t.Fatalf("%d: expected an ESError; got %v", tuple.status, err)
+		}
+
+		if expected, got := tuple.status, e.Status; expected != got {
+			t.Errorf("expected status = %d; got %d", expected, got)
+		}
+
+		if expected, got := tuple.expectedType, e.Type; expected != got {
+			t.Errorf("%d: expected type = %q; got %q", tuple.status, expected, got)
+		}
+
+		if expected, got := tuple.expectedReason, e.Reason; expected != got {
+			t.Errorf("%d: expected reason = %q; got %q", tuple.status, expected, got)
+		}
+
+		if expected, got := "Elasticsearch", e.Response.Header.Get("X-Elastic-Product"); expected != got {
+			t.Errorf("%d: expected the response headers on the error; got %v", tuple.status, e.Response.Header)
+		}
+
+		body, err := ioutil.ReadAll(e.Response.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, got := tuple.body, string(body); expected != got {
+			t.Errorf("%d: expected body %s; got %s", tuple.status, expected, got)
+		}
+	}
+}
+
+func TestIgnoreNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/twitter/_doc/1":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"_index":"twitter","_id":"1","_version":1,"result":"not_found","found":false}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error":{"type":"exception","reason":"boom"},"status":500}`))
+		}
+	}))
+	defer server.Close()
+
+	node := es.NewNode(server.URL, time.Second)
+
+	var response es.IndexResponse
+	_, err := node.Fire(es.DeleteRequest{es.IndexParams{Index: "twitter", Id: "1"}}, &response)
+	if !es.IsNotFound(err) {
+		t.Fatalf("expected a not found error; got %v", err)
+	}
+
+	if err := es.IgnoreNotFound(err); err != nil {
+		t.Errorf("expected the not found error to be ignored; got %v", err)
+	}
+
+	if response.Found {
+		t.Errorf("expected found = false")
+	}
+
+	if expected, got := "not_found", response.Result; expected != got {
+		t.Errorf("expected result = %q; got %q", expected, got)
+	}
+
+	_, err = node.Fire(es.DeleteRequest{es.IndexParams{Index: "twitter", Id: "2"}}, &es.IndexResponse{})
+	if err := es.IgnoreNotFound(err); err == nil {
+		t.Errorf("expected errors other than not found to be returned")
+	}
+}
@@ -0,0 +1,185 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+// http://www.elasticsearch.org/guide/reference/api/get.html
+type GetParams struct {
+	Fields     string // comma-separated stored fields
+	Preference string
+	Realtime   string
+	Refresh    string
+	Routing    string
+
+	// Source is "true", "false", or a comma-separated list of fields to
+	// return from the document's source.
+	Source         string
+	SourceIncludes string
+	SourceExcludes string
+
+	CommonParams
+}
+
+func (p GetParams) Values() url.Values {
+	return p.CommonParams.merge(values(map[string]string{
+		"fields":          p.Fields,
+		"preference":      p.Preference,
+		"realtime":        p.Realtime,
+		"refresh":         p.Refresh,
+		"routing":         p.Routing,
+		"_source":         p.Source,
+		"_source_include": p.SourceIncludes,
+		"_source_exclude": p.SourceExcludes,
+	}))
+}
+
+type GetRequest struct {
+	Index  string
+	Type   string
+	Id     string
+	Params GetParams
+}
+
+// WithRouting returns the request with the given routing, unless its Params
+// already set one.
+func (r GetRequest) WithRouting(routing string) GetRequest {
+	if r.Params.Routing == "" {
+		r.Params.Routing = routing
+	}
+	return r
+}
+
+func (r GetRequest) Path() string {
+	return docPath(r.Index, r.Type, r.Id, "")
+}
+
+func (r GetRequest) Request(uri *url.URL) (*http.Request, error) {
+	uri.Path = r.Path()
+	uri.RawQuery = r.Params.Values().Encode()
+
+	return http.NewRequest("GET", uri.String(), nil)
+}
+
+// ExistsRequest checks whether a document exists, without fetching it. Its
+// response has no body; use the Exists method of a Node or Cluster.
+type ExistsRequest struct {
+	Index  string
+	Type   string
+	Id     string
+	Params GetParams
+}
+
+func (r ExistsRequest) Path() string {
+	return docPath(r.Index, r.Type, r.Id, "")
+}
+
+func (r ExistsRequest) Request(uri *url.URL) (*http.Request, error) {
+	uri.Path = r.Path()
+	uri.RawQuery = r.Params.Values().Encode()
+
+	return http.NewRequest("HEAD", uri.String(), nil)
+}
+
+// exists interprets the response to an ExistsRequest or IndexExistsRequest.
+func exists(r *http.Response, err error) (bool, error) {
+	if IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	r.Body.Close()
+	return true, nil
+}
+
+// GetResponse is the response to a GetRequest. Unmarshal Source into your own
+// type. For a missing document, Fire returns an error satisfying IsNotFound,
+// along with the decoded response, with Found false; use IgnoreNotFound to
+// treat it as a success.
+type GetResponse struct {
+	Index   string          `json:"_index"`
+	Type    string          `json:"_type"`
+	ID      string          `json:"_id"`
+	Version int64           `json:"_version"`
+	Found   bool            `json:"found"`
+	Source  json.RawMessage `json:"_source,omitempty"`
+
+	SeqNo       int64 `json:"_seq_no,omitempty"`
+	PrimaryTerm int64 `json:"_primary_term,omitempty"`
+
+	Error  string `json:"error,omitempty"`
+	Status int    `json:"status,omitempty"`
+}
+
+//
+//
+//
+
+// MultiGetDoc identifies one document in a MultiGetRequest. Source, if set,
+// selects the fields of its source to return, as in SearchRequest.Source.
+type MultiGetDoc struct {
+	Index   string      `json:"_index,omitempty"`
+	Type    string      `json:"_type,omitempty"`
+	Id      string      `json:"_id"`
+	Routing string      `json:"routing,omitempty"`
+	Source  interface{} `json:"_source,omitempty"`
+}
+
+// http://www.elasticsearch.org/guide/reference/api/multi-get.html
+//
+// Index and Type are the defaults for Docs which don't set their own.
+type MultiGetRequest struct {
+	Index  string
+	Type   string
+	Docs   []MultiGetDoc
+	Params GetParams
+}
+
+// Add appends the document to the request. If any fields are given, only
+// they are returned from its source.
+func (r *MultiGetRequest) Add(index, typ, id string, fields ...string) {
+	doc := MultiGetDoc{Index: index, Type: typ, Id: id}
+	if len(fields) > 0 {
+		doc.Source = fields
+	}
+	r.Docs = append(r.Docs, doc)
+}
+
+func (r MultiGetRequest) Path() string {
+	return path.Join("/", r.Index, r.Type, "_mget")
+}
+
+func (r MultiGetRequest) EncodeSource(enc *json.Encoder) error {
+	return enc.Encode(map[string][]MultiGetDoc{
+		"docs": r.Docs,
+	})
+}
+
+func (r MultiGetRequest) Request(uri *url.URL) (*http.Request, error) {
+	uri.Path = r.Path()
+	uri.RawQuery = r.Params.Values().Encode()
+
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+
+	if err := r.EncodeSource(enc); err != nil {
+		return nil, err
+	}
+
+	return http.NewRequest("POST", uri.String(), buf)
+}
+
+// MultiGetResponse holds a GetResponse for each of the requested Docs, in
+// request order.
+type MultiGetResponse struct {
+	Docs []GetResponse `json:"docs"`
+
+	Error  string `json:"error,omitempty"`
+	Status int    `json:"status,omitempty"`
+}
@@ -0,0 +1,217 @@
+package elasticsearch_test
+
+import (
+	"encoding/json"
+	es "github.com/peterbourgon/elasticsearch"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestGetRequest(t *testing.T) {
+	request, err := es.GetRequest{
+		Index:  "twitter",
+		Type:   "tweet",
+		Id:     "1",
+		Params: es.GetParams{Routing: "kimchy", Source: "user,message"},
+	}.Request(&url.URL{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "GET", request.Method; expected != got {
+		t.Errorf("expected method = %q; got %q", expected, got)
+	}
+
+	if expected, got := "/twitter/tweet/1", request.URL.Path; expected != got {
+		t.Errorf("expected path = %q; got %q", expected, got)
+	}
+
+	if expected, got := "_source=user%2Cmessage&routing=kimchy", request.URL.RawQuery; expected != got {
+		t.Errorf("expected query = %q; got %q", expected, got)
+	}
+
+	if request.Body != nil {
+		t.Errorf("expected no body")
+	}
+}
+
+func TestGetResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/twitter/tweet/1":
+			w.Write([]byte(`{"_index":"twitter","_type":"tweet","_id":"1","_version":3,"found":true,"_source":{"user":"kimchy"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"_index":"twitter","_type":"tweet","_id":"2","found":false}`))
+		}
+	}))
+	defer server.Close()
+
+	node := es.NewNode(server.URL, time.Second)
+
+	var found es.GetResponse
+	if _, err := node.Fire(es.GetRequest{Index: "twitter", Type: "tweet", Id: "1"}, &found); err != nil {
+		t.Fatal(err)
+	}
+
+	if !found.Found {
+		t.Errorf("expected found = true")
+	}
+
+	if expected, got := int64(3), found.Version; expected != got {
+		t.Errorf("expected version = %d; got %d", expected, got)
+	}
+
+	if expected, got := `{"user":"kimchy"}`, string(found.Source); expected != got {
+		t.Errorf("expected source = %s; got %s", expected, got)
+	}
+
+	var missing es.GetResponse
+	response, err := node.Fire(es.GetRequest{Index: "twitter", Type: "tweet", Id: "2"}, &missing)
+	if e, ok := err.(*es.ESError); !ok || e.Status != http.StatusNotFound {
+		t.Fatalf("expected a 404 ESError; got %v", err)
+	}
+
+	if expected, got := http.StatusNotFound, response.StatusCode; expected != got {
+		t.Errorf("expected status = %d; got %d", expected, got)
+	}
+
+	if missing.Found {
+		t.Errorf("expected found = false")
+	}
+
+	if len(missing.Source) != 0 {
+		t.Errorf("expected no source; got %s", missing.Source)
+	}
+}
+
+func TestMultiGetRequest(t *testing.T) {
+	var r es.MultiGetRequest
+	r.Add("twitter", "tweet", "2")
+	r.Add("twitter", "tweet", "1", "user", "message")
+	r.Add("blog", "", "3")
+
+	request, err := r.Request(&url.URL{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "POST", request.Method; expected != got {
+		t.Errorf("expected method = %q; got %q", expected, got)
+	}
+
+	if expected, got := "/_mget", request.URL.Path; expected != got {
+		t.Errorf("expected path = %q; got %q", expected, got)
+	}
+
+	got, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"docs":[` +
+		`{"_index":"twitter","_type":"tweet","_id":"2"},` +
+		`{"_index":"twitter","_type":"tweet","_id":"1","_source":["user","message"]},` +
+		`{"_index":"blog","_id":"3"}` +
+		`]}` + "\n"
+
+	if expected != string(got) {
+		t.Errorf("expected body = %s; got %s", expected, got)
+	}
+}
+
+func TestMultiGetResponse(t *testing.T) {
+	body := `{"docs":[
+		{"_index":"twitter","_type":"tweet","_id":"2","found":false},
+		{"_index":"twitter","_type":"tweet","_id":"1","_version":1,"found":true,"_source":{"user":"kimchy"}}
+	]}`
+
+	var response es.MultiGetResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := 2, len(response.Docs); expected != got {
+		t.Fatalf("expected %d doc(s); got %d", expected, got)
+	}
+
+	for i, expected := range []bool{false, true} {
+		if got := response.Docs[i].Found; expected != got {
+			t.Errorf("doc %d: expected found = %v; got %v", i, expected, got)
+		}
+	}
+}
+
+func TestExists(t *testing.T) {
+	var method string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		switch r.URL.Path {
+		case "/twitter/tweet/1":
+			w.WriteHeader(http.StatusOK)
+		case "/twitter/tweet/2":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	node := es.NewNode(server.URL, time.Second)
+
+	for _, tuple := range []struct {
+		id       string
+		expected bool
+		err      bool
+	}{
+		{"1", true, false},
+		{"2", false, false},
+		{"3", false, true},
+	} {
+		ok, err := node.Exists(es.ExistsRequest{Index: "twitter", Type: "tweet", Id: tuple.id})
+
+		if expected, got := "HEAD", method; expected != got {
+			t.Errorf("%s: expected method = %q; got %q", tuple.id, expected, got)
+		}
+
+		if tuple.err != (err != nil) {
+			t.Errorf("%s: expected error = %v; got %v", tuple.id, tuple.err, err)
+		}
+
+		if expected, got := tuple.expected, ok; expected != got {
+			t.Errorf("%s: expected exists = %v; got %v", tuple.id, expected, got)
+		}
+	}
+}
+
+func TestMultiGetRequestAcrossIndices(t *testing.T) {
+	request, err := es.MultiGetRequest{
+		Docs: []es.MultiGetDoc{
+			{Index: "twitter", Type: "tweet", Id: "1"},
+			{Index: "blog", Id: "1", Routing: "bob"},
+		},
+	}.Request(&url.URL{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "/_mget", request.URL.Path; expected != got {
+		t.Errorf("expected path = %q; got %q", expected, got)
+	}
+
+	got, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"docs":[{"_index":"twitter","_type":"tweet","_id":"1"},{"_index":"blog","_id":"1","routing":"bob"}]}` + "\n"
+	if expected != string(got) {
+		t.Errorf("expected body = %s; got %s", expected, got)
+	}
+}
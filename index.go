@@ -4,27 +4,42 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
-	"path"
+	"strconv"
+	"strings"
 )
 
 type BulkResponse struct {
-	Took int `json:"took"` // ms
+	Took   int  `json:"took"`   // ms
+	Errors bool `json:"errors"` // if any item failed
 
 	Items []BulkItemResponse `json:"items"`
 }
 
+// ParseBulkResponse decodes a bulk response from r. Each item's Status and
+// Error report whether that operation failed.
+func ParseBulkResponse(r io.Reader) (*BulkResponse, error) {
+	var response BulkResponse
+	if err := json.NewDecoder(r).Decode(&response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
 type BulkItemResponse IndexResponse
 
 // Bulk responses are wrapped in an extra object whose only key is the
-// operation performed (create, delete, or index). BulkItemResponse response is
+// operation performed (create, delete, index, or update). BulkItemResponse response is
 // an alias for IndexResponse, but deals with this extra indirection.
 func (r *BulkItemResponse) UnmarshalJSON(data []byte) error {
 	var wrapper struct {
 		Create json.RawMessage `json:"create"`
 		Delete json.RawMessage `json:"delete"`
 		Index  json.RawMessage `json:"index"`
+		Update json.RawMessage `json:"update"`
 	}
 
 	if err := json.Unmarshal(data, &wrapper); err != nil {
@@ -40,14 +55,91 @@ func (r *BulkItemResponse) UnmarshalJSON(data []byte) error {
 		inner = wrapper.Index
 	case wrapper.Delete != nil:
 		inner = wrapper.Delete
+	case wrapper.Update != nil:
+		inner = wrapper.Update
 	default:
-		return fmt.Errorf("expected bulk response to be create, index, or delete")
+		return fmt.Errorf("expected bulk response to be create, index, delete, or update")
 	}
 
-	if err := json.Unmarshal(inner, (*IndexResponse)(r)); err != nil {
+	var v struct {
+		*IndexResponse
+		Error json.RawMessage `json:"error"` // a string, or an object
+	}
+	v.IndexResponse = (*IndexResponse)(r)
+
+	if err := json.Unmarshal(inner, &v); err != nil {
 		return err
 	}
 
+	r.ErrorType, r.Error = errorCause(v.Error)
+	return nil
+}
+
+// IsVersionConflict reports whether the operation failed due to a version
+// conflict.
+func (r BulkItemResponse) IsVersionConflict() bool {
+	return r.Status == http.StatusConflict || r.ErrorType == "version_conflict_engine_exception"
+}
+
+// StreamBulkResponse decodes a bulk response from r one item at a time,
+// passing each to f, so that memory use doesn't grow with the number of items.
+// Decoding stops at the first error returned by f.
+func StreamBulkResponse(r io.Reader, f func(BulkItemResponse) error) error {
+	dec := json.NewDecoder(r)
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		if key, _ := token.(string); key != "items" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := expectDelim(dec, '['); err != nil {
+			return err
+		}
+
+		for dec.More() {
+			var item BulkItemResponse
+			if err := dec.Decode(&item); err != nil {
+				return err
+			}
+
+			if err := f(item); err != nil {
+				return err
+			}
+		}
+
+		if err := expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+// Helper function which consumes the next token from dec, which must be the
+// delimiter d.
+func expectDelim(dec *json.Decoder, d json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if token != d {
+		return fmt.Errorf("expected %s; got %v", d, token)
+	}
+
 	return nil
 }
 
@@ -57,17 +149,59 @@ type IndexResponse struct {
 	Index   string `json:"_index"`
 	OK      bool   `json:"ok"`
 	Type    string `json:"_type"`
-	Version int    `json:"_version"`
+	Version int64  `json:"_version"`
+
+	// SeqNo and PrimaryTerm, from newer versions of ElasticSearch, identify
+	// the change made, eg. for IndexParams.SetIfSeqNo.
+	SeqNo       int64 `json:"_seq_no,omitempty"`
+	PrimaryTerm int64 `json:"_primary_term,omitempty"`
+
+	// Result is eg. "created", "updated", "deleted", or "noop". Older
+	// versions of ElasticSearch only report whether an index Created the
+	// document; ParseIndexResponse fills in each from the other.
+	Created bool   `json:"created"`
+	Result  string `json:"result,omitempty"`
 
 	Error    string `json:"error,omitempty"`
 	Status   int    `json:"status,omitempty"`
 	TimedOut bool   `json:"timed_out,omitempty"`
+
+	// ErrorType is the type of the Error of a bulk item, where ElasticSearch
+	// reports it, eg. version_conflict_engine_exception.
+	ErrorType string `json:"-"`
+}
+
+// ParseIndexResponse decodes the response to an index, create, update, or
+// delete from r, in either the older (created) or newer (result) shape.
+func ParseIndexResponse(r io.Reader) (*IndexResponse, error) {
+	var v struct {
+		IndexResponse
+		Created *bool `json:"created"`
+	}
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return nil, err
+	}
+
+	response := v.IndexResponse
+	switch {
+	case v.Created != nil:
+		response.Created = *v.Created
+		if response.Result == "" && response.Created {
+			response.Result = "created"
+		} else if response.Result == "" {
+			response.Result = "updated"
+		}
+	case response.Result == "created":
+		response.Created = true
+	}
+
+	return &response, nil
 }
 
 type IndexParams struct {
 	Index string `json:"_index"`
-	Type  string `json:"_type"`
-	Id    string `json:"_id"`
+	Type  string `json:"_type,omitempty"` // empty for typeless indices
+	Id    string `json:"_id,omitempty"`   // empty for ElasticSearch to assign
 
 	Consistency string `json:"_consistency,omitempty"`
 	Parent      string `json:"_parent,omitempty"`
@@ -79,10 +213,18 @@ type IndexParams struct {
 	Timestamp   string `json:"_timestamp,omitempty"`
 	Version     string `json:"_version,omitempty"`
 	VersionType string `json:"_version_type,omitempty"`
+
+	// IfSeqNo and IfPrimaryTerm, set together, make the write conditional on
+	// the document's last change, for newer versions of ElasticSearch. "0" is
+	// a valid sequence number. See SetIfSeqNo.
+	IfSeqNo       string `json:"if_seq_no,omitempty"`
+	IfPrimaryTerm string `json:"if_primary_term,omitempty"`
+
+	CommonParams
 }
 
 func (p IndexParams) Values() url.Values {
-	return values(map[string]string{
+	return p.CommonParams.merge(values(map[string]string{
 		"consistency":  p.Consistency,
 		"parent":       p.Parent,
 		"percolate":    p.Percolate,
@@ -93,36 +235,234 @@ func (p IndexParams) Values() url.Values {
 		"timestamp":    p.Timestamp,
 		"version":      p.Version,
 		"version_type": p.VersionType,
+
+		"if_seq_no":       p.IfSeqNo,
+		"if_primary_term": p.IfPrimaryTerm,
+	}))
+}
+
+// validateVersion checks that the version and version type are consistent:
+// the external version types require a numeric version, and a sequence number
+// needs its primary term.
+func (p IndexParams) validateVersion() error {
+	if p.Version != "" {
+		if _, err := strconv.ParseInt(p.Version, 10, 64); err != nil {
+			return fmt.Errorf("invalid version %q", p.Version)
+		}
+	}
+
+	switch p.VersionType {
+	case "", "internal", "force":
+	case "external", "external_gt", "external_gte":
+		if p.Version == "" {
+			return fmt.Errorf("version_type %s requires a version", p.VersionType)
+		}
+	default:
+		return oneOf("version_type", p.VersionType, "internal", "external", "external_gt", "external_gte", "force")
+	}
+
+	if (p.IfSeqNo == "") != (p.IfPrimaryTerm == "") {
+		return fmt.Errorf("if_seq_no and if_primary_term must be set together")
+	}
+
+	if _, err := strconv.ParseInt(p.IfSeqNo, 10, 64); p.IfSeqNo != "" && err != nil {
+		return fmt.Errorf("invalid if_seq_no %q", p.IfSeqNo)
+	}
+
+	if _, err := strconv.ParseInt(p.IfPrimaryTerm, 10, 64); p.IfPrimaryTerm != "" && err != nil {
+		return fmt.Errorf("invalid if_primary_term %q", p.IfPrimaryTerm)
+	}
+
+	return nil
+}
+
+// withRouting returns p with the routing, unless p already sets its own.
+func (p IndexParams) withRouting(routing string) IndexParams {
+	if p.Routing == "" {
+		p.Routing = routing
+	}
+	return p
+}
+
+// SetConsistency sets the write consistency, which must be one, quorum, or
+// all.
+func (p *IndexParams) SetConsistency(consistency string) error {
+	if err := oneOf("consistency", consistency, "one", "quorum", "all"); err != nil {
+		return err
+	}
+	p.Consistency = consistency
+	return nil
+}
+
+// SetRefresh sets the refresh policy, which must be true, false, or wait_for.
+func (p *IndexParams) SetRefresh(refresh string) error {
+	if err := oneOf("refresh", refresh, "true", "false", "wait_for"); err != nil {
+		return err
+	}
+	p.Refresh = refresh
+	return nil
+}
+
+// SetReplication sets the replication type, which must be sync or async.
+func (p *IndexParams) SetReplication(replication string) error {
+	if err := oneOf("replication", replication, "sync", "async"); err != nil {
+		return err
+	}
+	p.Replication = replication
+	return nil
+}
+
+// SetVersionType sets the versioning type, which must be internal, external,
+// external_gt, external_gte, or force.
+func (p *IndexParams) SetVersionType(versionType string) error {
+	if err := oneOf("version_type", versionType, "internal", "external", "external_gt", "external_gte", "force"); err != nil {
+		return err
+	}
+	p.VersionType = versionType
+	return nil
+}
+
+// SetVersion sets the version, and version type, for optimistic concurrency
+// control. A zero version is left unset, rather than sent as 0, which
+// ElasticSearch rejects for internal versioning. An empty version type is the
+// default, internal.
+func (p *IndexParams) SetVersion(version int64, versionType string) error {
+	if versionType != "" {
+		if err := p.SetVersionType(versionType); err != nil {
+			return err
+		}
+	}
+
+	p.Version = ""
+	if version != 0 {
+		p.Version = strconv.FormatInt(version, 10)
+	}
+	return nil
+}
+
+// SetIfSeqNo makes the write conditional on the document's sequence number
+// and primary term, eg. from a Hit requested with SeqNoPrimaryTerm.
+func (p *IndexParams) SetIfSeqNo(seqNo, primaryTerm int64) {
+	p.IfSeqNo = strconv.FormatInt(seqNo, 10)
+	p.IfPrimaryTerm = strconv.FormatInt(primaryTerm, 10)
+}
+
+// Helper function which encodes the bulk action metadata line for a request,
+// after checking its version params are consistent.
+func encodeBulkHeader(enc *json.Encoder, action string, p IndexParams) error {
+	if err := p.validateVersion(); err != nil {
+		return err
+	}
+
+	return enc.Encode(map[string]IndexParams{
+		action: p,
 	})
 }
 
+// Helper function which builds the path for a single document, eg.
+// "/twitter/tweet/1/_update". Typeless documents, with an empty typ, use the
+// paths of ElasticSearch 7 and later: "/twitter/_doc/1", "/twitter/_update/1".
+// Empty segments are left out, rather than producing "//".
+func docPath(index, typ, id, endpoint string) string {
+	segments := []string{index}
+	switch {
+	case typ != "":
+		segments = append(segments, typ, id, endpoint)
+	case endpoint != "":
+		segments = append(segments, endpoint, id)
+	default:
+		segments = append(segments, DocType, id)
+	}
+
+	a := []string{}
+	for _, segment := range segments {
+		if segment != "" {
+			a = append(a, segment)
+		}
+	}
+	return "/" + strings.Join(a, "/")
+}
+
+// Helper function which encodes a document source. A Source which is an
+// io.Reader must contain JSON, which is copied through rather than encoded.
+// Such sources are single-use: the request can only be encoded once.
+func encodeSource(enc *json.Encoder, source interface{}) error {
+	r, ok := source.(io.Reader)
+	if !ok {
+		return enc.Encode(source)
+	}
+
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return enc.Encode(json.RawMessage(buf))
+}
+
+// Helper function which returns the body of a single-document request. A
+// Source which is an io.Reader is streamed as-is; anything else is encoded.
+func sourceBody(source interface{}, encode func(*json.Encoder) error) (io.Reader, error) {
+	if r, ok := source.(io.Reader); ok {
+		return r, nil
+	}
+
+	buf := new(bytes.Buffer)
+	if err := encode(json.NewEncoder(buf)); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
 type IndexRequest struct {
 	Params IndexParams
-	Source interface{}
+	Source interface{} // anything JSON-marshalable, or an io.Reader of JSON
+}
+
+// WithRouting returns the request with the given routing, used both as a
+// single request and in a bulk, unless its Params already set one.
+func (r IndexRequest) WithRouting(routing string) IndexRequest {
+	r.Params = r.Params.withRouting(routing)
+	return r
 }
 
 func (r IndexRequest) EncodeBulkHeader(enc *json.Encoder) error {
-	return enc.Encode(map[string]IndexParams{
-		"index": r.Params,
-	})
+	return encodeBulkHeader(enc, "index", r.Params)
 }
 
 func (r IndexRequest) EncodeSource(enc *json.Encoder) error {
-	return enc.Encode(r.Source)
+	return encodeSource(enc, r.Source)
+}
+
+// Method returns the HTTP method for the request. Documents without an Id are
+// POSTed, so that ElasticSearch generates an id for them.
+func (r IndexRequest) Method() string {
+	if r.Params.Id == "" {
+		return "POST"
+	}
+
+	return "PUT"
+}
+
+func (r IndexRequest) Path() string {
+	return docPath(r.Params.Index, r.Params.Type, r.Params.Id, "")
 }
 
 func (r IndexRequest) Request(uri *url.URL) (*http.Request, error) {
-	uri.Path = path.Join("/", r.Params.Index, r.Params.Type, r.Params.Id)
-	uri.RawQuery = r.Params.Values().Encode()
+	if err := r.Params.validateVersion(); err != nil {
+		return nil, err
+	}
 
-	buf := new(bytes.Buffer)
-	enc := json.NewEncoder(buf)
+	uri.Path = r.Path()
+	uri.RawQuery = r.Params.Values().Encode()
 
-	if err := r.EncodeSource(enc); err != nil {
+	body, err := sourceBody(r.Source, r.EncodeSource)
+	if err != nil {
 		return nil, err
 	}
 
-	return http.NewRequest("PUT", uri.String(), buf)
+	return http.NewRequest(r.Method(), uri.String(), body)
 }
 
 type CreateRequest struct {
@@ -130,38 +470,62 @@ type CreateRequest struct {
 	Source interface{}
 }
 
+// WithRouting is as for IndexRequest.
+func (r CreateRequest) WithRouting(routing string) CreateRequest {
+	r.Params = r.Params.withRouting(routing)
+	return r
+}
+
 func (r CreateRequest) EncodeBulkHeader(enc *json.Encoder) error {
-	return enc.Encode(map[string]IndexParams{
-		"create": r.Params,
-	})
+	return encodeBulkHeader(enc, "create", r.Params)
 }
 
 func (r CreateRequest) EncodeSource(enc *json.Encoder) error {
-	return enc.Encode(r.Source)
+	return encodeSource(enc, r.Source)
 }
 
 func (r CreateRequest) Request(uri *url.URL) (*http.Request, error) {
-	uri.Path = path.Join("/", r.Params.Index, r.Params.Type, r.Params.Id, "_create")
-	uri.RawQuery = r.Params.Values().Encode()
+	if err := r.Params.validateVersion(); err != nil {
+		return nil, err
+	}
 
-	buf := new(bytes.Buffer)
-	enc := json.NewEncoder(buf)
+	uri.Path = docPath(r.Params.Index, r.Params.Type, r.Params.Id, "_create")
+	uri.RawQuery = r.Params.Values().Encode()
 
-	if err := r.EncodeSource(enc); err != nil {
+	body, err := sourceBody(r.Source, r.EncodeSource)
+	if err != nil {
 		return nil, err
 	}
 
-	return http.NewRequest("PUT", uri.String(), buf)
+	return http.NewRequest("PUT", uri.String(), body)
 }
 
 type DeleteRequest struct {
 	Params IndexParams
 }
 
+// Validate returns an error if the request doesn't identify a single
+// document. Without an Id, the DELETE would hit the type (or index) itself.
+func (r DeleteRequest) Validate() error {
+	if r.Params.Id == "" {
+		return fmt.Errorf("delete request requires an id")
+	}
+
+	return nil
+}
+
+// WithRouting is as for IndexRequest.
+func (r DeleteRequest) WithRouting(routing string) DeleteRequest {
+	r.Params = r.Params.withRouting(routing)
+	return r
+}
+
 func (r DeleteRequest) EncodeBulkHeader(enc *json.Encoder) error {
-	return enc.Encode(map[string]IndexParams{
-		"delete": r.Params,
-	})
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	return encodeBulkHeader(enc, "delete", r.Params)
 }
 
 func (r DeleteRequest) EncodeSource(enc *json.Encoder) error {
@@ -169,7 +533,15 @@ func (r DeleteRequest) EncodeSource(enc *json.Encoder) error {
 }
 
 func (r DeleteRequest) Request(uri *url.URL) (*http.Request, error) {
-	uri.Path = path.Join("/", r.Params.Index, r.Params.Type, r.Params.Id)
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := r.Params.validateVersion(); err != nil {
+		return nil, err
+	}
+
+	uri.Path = docPath(r.Params.Index, r.Params.Type, r.Params.Id, "")
 	uri.RawQuery = r.Params.Values().Encode()
 
 	return http.NewRequest("DELETE", uri.String(), nil)
@@ -177,20 +549,105 @@ func (r DeleteRequest) Request(uri *url.URL) (*http.Request, error) {
 
 type UpdateRequest struct {
 	Params IndexParams
+
+	// Source is the partial document merged into the existing one. If none of
+	// Script, Upsert or DocAsUpsert are set, it's sent as the whole update
+	// body instead, eg. {"doc": {...}} or {"script": "..."}.
 	Source interface{}
+
+	// Script updates the document with a script, given ScriptParams. It
+	// can't be combined with a Source.
+	Script       string
+	ScriptParams map[string]interface{}
+
+	// Upsert is indexed if the document doesn't exist yet. DocAsUpsert
+	// indexes the Source instead.
+	Upsert      interface{}
+	DocAsUpsert bool
+}
+
+// WithRouting is as for IndexRequest.
+func (r UpdateRequest) WithRouting(routing string) UpdateRequest {
+	r.Params = r.Params.withRouting(routing)
+	return r
+}
+
+func (r UpdateRequest) EncodeBulkHeader(enc *json.Encoder) error {
+	return encodeBulkHeader(enc, "update", r.Params)
+}
+
+func (r UpdateRequest) EncodeSource(enc *json.Encoder) error {
+	body, err := r.body()
+	if err != nil {
+		return err
+	}
+
+	return encodeSource(enc, body)
+}
+
+// body returns the update body: the Source as-is, or one built from the
+// Script, Upsert and DocAsUpsert fields, with the Source as its doc.
+func (r UpdateRequest) body() (interface{}, error) {
+	if r.Script == "" && r.Upsert == nil && !r.DocAsUpsert {
+		return r.Source, nil
+	}
+
+	if r.Script != "" && r.Source != nil {
+		return nil, fmt.Errorf("update can't have both a script and a doc")
+	}
+
+	body := map[string]interface{}{}
+
+	if r.Source != nil {
+		doc := r.Source
+		if rd, ok := doc.(io.Reader); ok {
+			buf, err := ioutil.ReadAll(rd)
+			if err != nil {
+				return nil, err
+			}
+			doc = json.RawMessage(buf)
+		}
+		body["doc"] = doc
+	}
+
+	if r.Script != "" {
+		script := map[string]interface{}{"source": r.Script}
+		if len(r.ScriptParams) > 0 {
+			script["params"] = r.ScriptParams
+		}
+		body["script"] = script
+	}
+
+	if r.Upsert != nil {
+		body["upsert"] = r.Upsert
+	}
+
+	if r.DocAsUpsert {
+		body["doc_as_upsert"] = true
+	}
+
+	return body, nil
 }
 
 func (r UpdateRequest) Request(uri *url.URL) (*http.Request, error) {
-	uri.Path = path.Join("/", r.Params.Index, r.Params.Type, r.Params.Id, "_update")
+	if err := r.Params.validateVersion(); err != nil {
+		return nil, err
+	}
+
+	uri.Path = docPath(r.Params.Index, r.Params.Type, r.Params.Id, "_update")
 	uri.RawQuery = r.Params.Values().Encode()
 
-	buf := new(bytes.Buffer)
+	source, err := r.body()
+	if err != nil {
+		return nil, err
+	}
 
-	if err := json.NewEncoder(buf).Encode(r.Source); err != nil {
+	body, err := sourceBody(source, func(enc *json.Encoder) error { return encodeSource(enc, source) })
+	if err != nil {
 		return nil, err
 	}
 
-	return http.NewRequest("POST", uri.String(), buf)
+	return http.NewRequest("POST", uri.String(), body)
 }
 
 //
@@ -201,14 +658,28 @@ type BulkParams struct {
 	Consistency string
 	Refresh     string
 	Replication string
+	Routing     string // default for items without their own routing
+
+	CommonParams
 }
 
 func (p BulkParams) Values() url.Values {
-	return values(map[string]string{
+	return p.CommonParams.merge(values(map[string]string{
 		"consistency": p.Consistency,
 		"refresh":     p.Refresh,
 		"replication": p.Replication,
-	})
+		"routing":     p.Routing,
+	}))
+}
+
+// SetRefresh sets the refresh policy for the whole bulk, which must be true,
+// false, or wait_for. Bulk items can't set their own.
+func (p *BulkParams) SetRefresh(refresh string) error {
+	if err := oneOf("refresh", refresh, "true", "false", "wait_for"); err != nil {
+		return err
+	}
+	p.Refresh = refresh
+	return nil
 }
 
 type BulkIndexable interface {
@@ -219,6 +690,129 @@ type BulkIndexable interface {
 type BulkRequest struct {
 	Params   BulkParams
 	Requests []BulkIndexable
+
+	// ForceType, if set, overrides the _type of every item in the bulk, eg.
+	// with DocType when migrating to typeless indices.
+	ForceType string
+
+	// IdGenerator, if set, is called for the _id of each index or create
+	// whose Id is empty, eg. to use time-sortable ids rather than those
+	// generated by ElasticSearch.
+	IdGenerator func() string
+}
+
+// DocType is the single type of documents in typeless indices.
+const DocType = "_doc"
+
+// NewBulk batches the passed requests into a BulkRequest with default params.
+func NewBulk(requests ...BulkIndexable) BulkRequest {
+	return BulkRequest{
+		Requests: requests,
+	}
+}
+
+// NewBulkIndex is NewBulk for a batch of IndexRequests.
+func NewBulkIndex(requests ...IndexRequest) BulkRequest {
+	a := make([]BulkIndexable, 0, len(requests))
+	for _, request := range requests {
+		a = append(a, request)
+	}
+	return NewBulk(a...)
+}
+
+// NewBulkDelete is NewBulk for a batch of DeleteRequests.
+func NewBulkDelete(requests ...DeleteRequest) BulkRequest {
+	a := make([]BulkIndexable, 0, len(requests))
+	for _, request := range requests {
+		a = append(a, request)
+	}
+	return NewBulk(a...)
+}
+
+// BulkDelete deletes the documents with the given ids from the index, in a
+// single bulk with the given params, and returns the response. Each of its
+// items reports whether the document was found. With no ids, nothing is sent.
+func BulkDelete(f Firer, index, typ string, ids []string, params BulkParams) (*BulkResponse, error) {
+	var response BulkResponse
+	if len(ids) == 0 {
+		return &response, nil
+	}
+
+	requests := make([]DeleteRequest, 0, len(ids))
+	for _, id := range ids {
+		requests = append(requests, DeleteRequest{IndexParams{Index: index, Type: typ, Id: id}})
+	}
+
+	bulk := NewBulkDelete(requests...)
+	bulk.Params = params
+
+	if _, err := f.Fire(bulk, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// BulkResult pairs a request in a bulk with the response to it.
+type BulkResult struct {
+	Request  BulkIndexable
+	Response BulkItemResponse
+}
+
+// Correlate pairs each request in the bulk with its item in the response,
+// which ElasticSearch returns in request order.
+func (r BulkRequest) Correlate(response *BulkResponse) ([]BulkResult, error) {
+	if len(r.Requests) != len(response.Items) {
+		return nil, fmt.Errorf("bulk of %d request(s) has %d response item(s)", len(r.Requests), len(response.Items))
+	}
+
+	results := make([]BulkResult, len(r.Requests))
+	for i, request := range r.Requests {
+		results[i] = BulkResult{
+			Request:  request,
+			Response: response.Items[i],
+		}
+	}
+	return results, nil
+}
+
+// RetryableFailures splits the failed items of the response to the bulk. The
+// requests which failed with one of the statuses, by default 429 Too Many
+// Requests and 503 Service Unavailable, are returned as a new bulk to retry,
+// with the same params. The other failures are returned as permanent.
+func (r BulkRequest) RetryableFailures(response *BulkResponse, statuses ...int) (BulkRequest, []BulkResult, error) {
+	results, err := r.Correlate(response)
+	if err != nil {
+		return BulkRequest{}, nil, err
+	}
+
+	if len(statuses) == 0 {
+		statuses = []int{http.StatusTooManyRequests, http.StatusServiceUnavailable}
+	}
+
+	retry, permanent := r, []BulkResult{}
+	retry.Requests = []BulkIndexable{}
+
+	for _, result := range results {
+		if result.Response.Error == "" {
+			continue
+		}
+
+		retryable := false
+		for _, status := range statuses {
+			if result.Response.Status == status {
+				retryable = true
+				break
+			}
+		}
+
+		if retryable {
+			retry.Requests = append(retry.Requests, result.Request)
+		} else {
+			permanent = append(permanent, result)
+		}
+	}
+
+	return retry, permanent, nil
 }
 
 func (r BulkRequest) Request(uri *url.URL) (*http.Request, error) {
@@ -229,7 +823,7 @@ func (r BulkRequest) Request(uri *url.URL) (*http.Request, error) {
 	enc := json.NewEncoder(buf)
 
 	for _, req := range r.Requests {
-		if err := req.EncodeBulkHeader(enc); err != nil {
+		if err := r.encodeBulkHeader(enc, req); err != nil {
 			return nil, err
 		}
 
@@ -240,3 +834,44 @@ func (r BulkRequest) Request(uri *url.URL) (*http.Request, error) {
 
 	return http.NewRequest("PUT", uri.String(), buf)
 }
+
+// encodeBulkHeader encodes the header of req, with its _type replaced by the
+// bulk's ForceType, and any empty index or create _id filled in by its
+// IdGenerator.
+func (r BulkRequest) encodeBulkHeader(enc *json.Encoder, req BulkIndexable) error {
+	if r.ForceType == "" && r.IdGenerator == nil {
+		return req.EncodeBulkHeader(enc)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := req.EncodeBulkHeader(json.NewEncoder(buf)); err != nil {
+		return err
+	}
+
+	var header map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &header); err != nil {
+		return err
+	}
+
+	for action, meta := range header {
+		if r.ForceType != "" {
+			typ, err := json.Marshal(r.ForceType)
+			if err != nil {
+				return err
+			}
+			meta["_type"] = typ
+		}
+
+		if r.IdGenerator != nil && (action == "index" || action == "create") {
+			if id := meta["_id"]; id == nil || string(id) == `""` {
+				id, err := json.Marshal(r.IdGenerator())
+				if err != nil {
+					return err
+				}
+				meta["_id"] = id
+			}
+		}
+	}
+
+	return enc.Encode(header)
+}
@@ -2,9 +2,15 @@ package elasticsearch_test
 
 import (
 	"encoding/json"
+	"fmt"
 	es "github.com/peterbourgon/elasticsearch"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestIndexRequest(t *testing.T) {
@@ -70,6 +76,86 @@ func TestIndexRequest(t *testing.T) {
 	}
 }
 
+func TestIndexRequestWithoutId(t *testing.T) {
+	request, err := es.IndexRequest{
+		es.IndexParams{
+			Index: "twitter",
+			Type:  "tweet",
+		},
+		map[string]string{"user": "kimchy"},
+	}.Request(&url.URL{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "POST", request.Method; expected != got {
+		t.Errorf("expected method = %q; got %q", expected, got)
+	}
+
+	if expected, got := "/twitter/tweet", request.URL.Path; expected != got {
+		t.Errorf("expected path = %q; got %q", expected, got)
+	}
+}
+
+func TestIndexParamsSetters(t *testing.T) {
+	var p es.IndexParams
+
+	for _, tuple := range []struct {
+		set   func(string) error
+		value string
+		valid bool
+	}{
+		{p.SetVersionType, "external", true},
+		{p.SetVersionType, "external_gte", true},
+		{p.SetVersionType, "bogus", false},
+		{p.SetRefresh, "wait_for", true},
+		{p.SetRefresh, "yes", false},
+		{p.SetConsistency, "quorum", true},
+		{p.SetConsistency, "most", false},
+	} {
+		err := tuple.set(tuple.value)
+		if tuple.valid && err != nil {
+			t.Errorf("%q: expected no error; got %s", tuple.value, err)
+		}
+		if !tuple.valid && err == nil {
+			t.Errorf("%q: expected an error", tuple.value)
+		}
+	}
+
+	if expected, got := "external_gte", p.VersionType; expected != got {
+		t.Errorf("expected version_type = %q; got %q", expected, got)
+	}
+
+	if expected, got := "wait_for", p.Refresh; expected != got {
+		t.Errorf("expected refresh = %q; got %q", expected, got)
+	}
+
+	if expected, got := "quorum", p.Consistency; expected != got {
+		t.Errorf("expected consistency = %q; got %q", expected, got)
+	}
+}
+
+func TestIndexRequestCommonParams(t *testing.T) {
+	request, err := es.IndexRequest{
+		es.IndexParams{
+			Index:        "twitter",
+			Type:         "tweet",
+			Id:           "1",
+			CommonParams: es.CommonParams{Human: "true", ErrorTrace: "true"},
+		},
+		map[string]string{"user": "kimchy"},
+	}.Request(&url.URL{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "error_trace=true&human=true", request.URL.RawQuery; expected != got {
+		t.Errorf("expected query = %q; got %q", expected, got)
+	}
+}
+
 func TestCreateRequest(t *testing.T) {
 	doc := map[string]string{
 		"user":      "kimchy",
@@ -139,14 +225,14 @@ func TestUpdateRequest(t *testing.T) {
 	}
 
 	request, err := es.UpdateRequest{
-		es.IndexParams{
+		Params: es.IndexParams{
 			Index:     "twitter",
 			Type:      "tweet",
 			Id:        "1",
 			Percolate: "*",
 			Version:   "4",
 		},
-		doc,
+		Source: doc,
 	}.Request(&url.URL{})
 
 	if err != nil {
@@ -222,12 +308,38 @@ func TestDeleteRequest(t *testing.T) {
 	}
 }
 
+func TestDeleteRequestWithoutId(t *testing.T) {
+	_, err := es.DeleteRequest{
+		es.IndexParams{
+			Index: "twitter",
+			Type:  "tweet",
+		},
+	}.Request(&url.URL{})
+
+	if err == nil {
+		t.Fatal("expected an error for a delete request without an id")
+	}
+
+	_, err = es.BulkRequest{
+		Params: es.BulkParams{},
+		Requests: []es.BulkIndexable{
+			es.DeleteRequest{
+				es.IndexParams{Index: "twitter", Type: "tweet"},
+			},
+		},
+	}.Request(&url.URL{})
+
+	if err == nil {
+		t.Fatal("expected an error for a bulk delete without an id")
+	}
+}
+
 func TestBulkRequest(t *testing.T) {
 	request, err := es.BulkRequest{
-		es.BulkParams{
+		Params: es.BulkParams{
 			Consistency: "quorum",
 		},
-		[]es.BulkIndexable{
+		Requests: []es.BulkIndexable{
 			es.IndexRequest{
 				es.IndexParams{
 					Index:   "twitter",
@@ -368,3 +480,932 @@ func TestBulkRequest(t *testing.T) {
 		t.Errorf("expected _id = %q; got %q", expected, got)
 	}
 }
+
+func TestNewBulk(t *testing.T) {
+	for _, tuple := range []struct {
+		r        es.BulkRequest
+		expected []string
+	}{
+		{
+			r: es.NewBulk(
+				es.IndexRequest{
+					es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"},
+					map[string]string{"user": "kimchy"},
+				},
+				es.DeleteRequest{
+					es.IndexParams{Index: "twitter", Type: "tweet", Id: "2"},
+				},
+			),
+			expected: []string{
+				`{"index":{"_index":"twitter","_type":"tweet","_id":"1"}}`,
+				`{"user":"kimchy"}`,
+				`{"delete":{"_index":"twitter","_type":"tweet","_id":"2"}}`,
+			},
+		},
+		{
+			r: es.NewBulkIndex(
+				es.IndexRequest{
+					es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"},
+					map[string]string{"user": "kimchy"},
+				},
+			),
+			expected: []string{
+				`{"index":{"_index":"twitter","_type":"tweet","_id":"1"}}`,
+				`{"user":"kimchy"}`,
+			},
+		},
+		{
+			r: es.NewBulkDelete(
+				es.DeleteRequest{es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"}},
+				es.DeleteRequest{es.IndexParams{Index: "twitter", Type: "tweet", Id: "2"}},
+			),
+			expected: []string{
+				`{"delete":{"_index":"twitter","_type":"tweet","_id":"1"}}`,
+				`{"delete":{"_index":"twitter","_type":"tweet","_id":"2"}}`,
+			},
+		},
+	} {
+		request, err := tuple.r.Request(&url.URL{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ioutil.ReadAll(request.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected := strings.Join(tuple.expected, "\n") + "\n"; expected != string(got) {
+			t.Errorf("Body: expected:\n---\n%s\n---\ngot:\n---\n%s\n---\n", expected, got)
+		}
+	}
+}
+
+func TestStreamBulkResponse(t *testing.T) {
+	body := `{
+		"took": 30,
+		"items": [
+			{"index": {"_index": "twitter", "_type": "tweet", "_id": "1", "_version": 1, "ok": true}},
+			{"delete": {"_index": "twitter", "_type": "tweet", "_id": "2", "_version": 2, "ok": true, "found": true}},
+			{"create": {"_index": "twitter", "_type": "tweet", "_id": "3", "_version": 1, "ok": true}}
+		],
+		"errors": false
+	}`
+
+	var ids []string
+	err := es.StreamBulkResponse(strings.NewReader(body), func(item es.BulkItemResponse) error {
+		ids = append(ids, item.ID)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "1,2,3", strings.Join(ids, ","); expected != got {
+		t.Errorf("expected ids = %s; got %s", expected, got)
+	}
+}
+
+func TestBulkRequestBody(t *testing.T) {
+	request, err := es.NewBulk(
+		es.IndexRequest{
+			es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"},
+			map[string]string{"user": "kimchy"},
+		},
+		es.DeleteRequest{
+			es.IndexParams{Index: "twitter", Type: "tweet", Id: "2"},
+		},
+		es.UpdateRequest{
+			Params: es.IndexParams{Index: "twitter", Type: "tweet", Id: "3"},
+			Source: map[string]interface{}{"doc": map[string]string{"user": "bob"}},
+		},
+		es.CreateRequest{
+			es.IndexParams{Index: "twitter", Type: "tweet", Id: "4"},
+			map[string]string{"user": "alice"},
+		},
+	).Request(&url.URL{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"index":{"_index":"twitter","_type":"tweet","_id":"1"}}
+{"user":"kimchy"}
+{"delete":{"_index":"twitter","_type":"tweet","_id":"2"}}
+{"update":{"_index":"twitter","_type":"tweet","_id":"3"}}
+{"doc":{"user":"bob"}}
+{"create":{"_index":"twitter","_type":"tweet","_id":"4"}}
+{"user":"alice"}
+`
+
+	got, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected != string(got) {
+		t.Errorf("Body: expected:\n---\n%s\n---\ngot:\n---\n%s\n---\n", expected, got)
+	}
+}
+
+func TestBulkRequestForceType(t *testing.T) {
+	bulk := es.NewBulk(
+		es.IndexRequest{
+			es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"},
+			map[string]string{"user": "kimchy"},
+		},
+		es.DeleteRequest{
+			es.IndexParams{Index: "twitter", Id: "2"},
+		},
+	)
+	bulk.ForceType = es.DocType
+
+	request, err := bulk.Request(&url.URL{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"index":{"_id":"1","_index":"twitter","_type":"_doc"}}
+{"user":"kimchy"}
+{"delete":{"_id":"2","_index":"twitter","_type":"_doc"}}
+`
+
+	got, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected != string(got) {
+		t.Errorf("Body: expected:\n---\n%s\n---\ngot:\n---\n%s\n---\n", expected, got)
+	}
+}
+
+func TestBulkRequestIdGenerator(t *testing.T) {
+	n := 0
+	bulk := es.NewBulk(
+		es.IndexRequest{
+			es.IndexParams{Index: "logs", Type: "event"},
+			map[string]string{"msg": "a"},
+		},
+		es.IndexRequest{
+			es.IndexParams{Index: "logs", Type: "event", Id: "mine"},
+			map[string]string{"msg": "b"},
+		},
+		es.CreateRequest{
+			es.IndexParams{Index: "logs", Type: "event"},
+			map[string]string{"msg": "c"},
+		},
+	)
+	bulk.IdGenerator = func() string {
+		n++
+		return fmt.Sprintf("id-%d", n)
+	}
+
+	request, err := bulk.Request(&url.URL{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"index":{"_id":"id-1","_index":"logs","_type":"event"}}
+{"msg":"a"}
+{"index":{"_id":"mine","_index":"logs","_type":"event"}}
+{"msg":"b"}
+{"create":{"_id":"id-2","_index":"logs","_type":"event"}}
+{"msg":"c"}
+`
+
+	got, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected != string(got) {
+		t.Errorf("Body: expected:\n---\n%s\n---\ngot:\n---\n%s\n---\n", expected, got)
+	}
+}
+
+func TestBulkRequestWithoutId(t *testing.T) {
+	request, err := es.NewBulk(
+		es.IndexRequest{
+			es.IndexParams{Index: "logs", Type: "event"},
+			map[string]string{"msg": "a"},
+		},
+	).Request(&url.URL{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"index":{"_index":"logs","_type":"event"}}
+{"msg":"a"}
+`
+
+	got, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected != string(got) {
+		t.Errorf("Body: expected:\n---\n%s\n---\ngot:\n---\n%s\n---\n", expected, got)
+	}
+}
+
+func TestBulkRequestRouting(t *testing.T) {
+	request, err := es.BulkRequest{
+		Params: es.BulkParams{Routing: "shared"},
+		Requests: []es.BulkIndexable{
+			es.IndexRequest{
+				es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"},
+				map[string]string{"user": "kimchy"},
+			},
+			es.IndexRequest{
+				es.IndexParams{Index: "twitter", Type: "tweet", Id: "2", Routing: "own"},
+				map[string]string{"user": "bob"},
+			},
+		},
+	}.Request(&url.URL{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "shared", request.URL.Query().Get("routing"); expected != got {
+		t.Errorf("expected routing = %q; got %q", expected, got)
+	}
+
+	decoder := json.NewDecoder(request.Body)
+	var header struct {
+		Index map[string]string `json:"index"`
+	}
+	var body map[string]string
+
+	for _, expected := range []string{"", "own"} {
+		if err := decoder.Decode(&header); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := header.Index["_routing"]; expected != got {
+			t.Errorf("expected _routing = %q; got %q", expected, got)
+		}
+
+		if err := decoder.Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBulkRequestCorrelate(t *testing.T) {
+	bulk := es.NewBulk(
+		es.IndexRequest{
+			es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"},
+			map[string]string{"user": "kimchy"},
+		},
+		es.DeleteRequest{
+			es.IndexParams{Index: "twitter", Type: "tweet", Id: "2"},
+		},
+	)
+
+	var response es.BulkResponse
+	if err := json.Unmarshal([]byte(`{
+		"took": 3,
+		"items": [
+			{"index": {"_index": "twitter", "_type": "tweet", "_id": "1", "_version": 1}},
+			{"delete": {"_index": "twitter", "_type": "tweet", "_id": "2", "found": false}}
+		]
+	}`), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := bulk.Correlate(&response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := results[0].Request.(es.IndexRequest); !ok {
+		t.Errorf("expected an index request; got %v", results[0].Request)
+	}
+
+	if _, ok := results[1].Request.(es.DeleteRequest); !ok {
+		t.Errorf("expected a delete request; got %v", results[1].Request)
+	}
+
+	if expected, got := "1", results[0].Response.ID; expected != got {
+		t.Errorf("expected _id = %q; got %q", expected, got)
+	}
+
+	if expected, got := "2", results[1].Response.ID; expected != got {
+		t.Errorf("expected _id = %q; got %q", expected, got)
+	}
+
+	response.Items = response.Items[:1]
+	if _, err := bulk.Correlate(&response); err == nil {
+		t.Errorf("expected an error for mismatched lengths")
+	}
+}
+
+func TestBulkRequestRetryableFailures(t *testing.T) {
+	bulk := es.NewBulkIndex(
+		es.IndexRequest{es.IndexParams{Index: "twitter", Id: "1"}, map[string]string{"user": "kimchy"}},
+		es.IndexRequest{es.IndexParams{Index: "twitter", Id: "2"}, map[string]string{"user": "bob"}},
+		es.IndexRequest{es.IndexParams{Index: "twitter", Id: "3"}, map[string]string{"age": "old"}},
+	)
+	bulk.Params.Refresh = "true"
+
+	var response es.BulkResponse
+	if err := json.Unmarshal([]byte(`{
+		"took": 3,
+		"errors": true,
+		"items": [
+			{"index": {"_index": "twitter", "_id": "1", "_version": 1, "status": 201}},
+			{"index": {"_index": "twitter", "_id": "2", "status": 429, "error": {"type": "es_rejected_execution_exception", "reason": "rejected execution"}}},
+			{"index": {"_index": "twitter", "_id": "3", "status": 400, "error": {"type": "mapper_parsing_exception", "reason": "failed to parse field [age]"}}}
+		]
+	}`), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	retry, permanent, err := bulk.RetryableFailures(&response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := 1, len(retry.Requests); expected != got {
+		t.Fatalf("expected %d request(s) to retry; got %d", expected, got)
+	}
+
+	if expected, got := "2", retry.Requests[0].(es.IndexRequest).Params.Id; expected != got {
+		t.Errorf("expected to retry _id %q; got %q", expected, got)
+	}
+
+	if expected, got := "true", retry.Params.Refresh; expected != got {
+		t.Errorf("expected the retry to keep refresh = %q; got %q", expected, got)
+	}
+
+	if expected, got := 1, len(permanent); expected != got {
+		t.Fatalf("expected %d permanent failure(s); got %d", expected, got)
+	}
+
+	if expected, got := 400, permanent[0].Response.Status; expected != got {
+		t.Errorf("expected permanent status %d; got %d", expected, got)
+	}
+
+	// Only the given statuses are retried.
+	retry, permanent, err = bulk.RetryableFailures(&response, 400)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := 1, len(retry.Requests); expected != got {
+		t.Fatalf("expected %d retryable request(s); got %d", expected, got)
+	}
+
+	if expected, got := "3", retry.Requests[0].(es.IndexRequest).Params.Id; expected != got {
+		t.Errorf("expected to retry only _id %q; got %q", expected, got)
+	}
+
+	if expected, got := 1, len(permanent); expected != got {
+		t.Fatalf("expected %d permanent failure(s); got %d", expected, got)
+	}
+
+	if expected, got := 429, permanent[0].Response.Status; expected != got {
+		t.Errorf("expected only the 429 to be permanent; got status %d", got)
+	}
+}
+
+func TestIndexRequestReaderSource(t *testing.T) {
+	source := `{"user":"kimchy","message":"trying out Elastic Search"}`
+	params := es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"}
+
+	request, err := es.IndexRequest{params, strings.NewReader(source)}.Request(&url.URL{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := source; expected != string(got) {
+		t.Errorf("expected body = %s; got %s", expected, got)
+	}
+
+	request, err = es.NewBulk(es.IndexRequest{params, strings.NewReader(source)}).Request(&url.URL{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = ioutil.ReadAll(request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"index":{"_index":"twitter","_type":"tweet","_id":"1"}}` + "\n" + source + "\n"
+	if expected != string(got) {
+		t.Errorf("Body: expected:\n---\n%s\n---\ngot:\n---\n%s\n---\n", expected, got)
+	}
+}
+
+func TestBulkRequestCommonParams(t *testing.T) {
+	request, err := es.BulkRequest{
+		Params: es.BulkParams{
+			Refresh: "true",
+			CommonParams: es.CommonParams{
+				FilterPath: "items.*.error",
+				Timeout:    "1m",
+			},
+		},
+	}.Request(&url.URL{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "filter_path=items.%2A.error&refresh=true&timeout=1m", request.URL.RawQuery; expected != got {
+		t.Errorf("expected query = %q; got %q", expected, got)
+	}
+}
+
+func TestBulkRequestExternalVersion(t *testing.T) {
+	request, err := es.NewBulk(
+		es.IndexRequest{
+			es.IndexParams{Index: "twitter", Type: "tweet", Id: "1", Version: "1380000000", VersionType: "external_gte"},
+			map[string]string{"user": "kimchy"},
+		},
+	).Request(&url.URL{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"index":{"_index":"twitter","_type":"tweet","_id":"1","_version":"1380000000","_version_type":"external_gte"}}
+{"user":"kimchy"}
+`
+	if expected != string(got) {
+		t.Errorf("Body: expected:\n---\n%s\n---\ngot:\n---\n%s\n---\n", expected, got)
+	}
+
+	for _, params := range []es.IndexParams{
+		{Index: "twitter", Type: "tweet", Id: "1", VersionType: "external"},
+		{Index: "twitter", Type: "tweet", Id: "1", Version: "one", VersionType: "external"},
+		{Index: "twitter", Type: "tweet", Id: "1", Version: "1", VersionType: "newest"},
+	} {
+		if _, err := es.NewBulk(es.IndexRequest{params, nil}).Request(&url.URL{}); err == nil {
+			t.Errorf("%+v: expected an error", params)
+		}
+	}
+}
+
+func TestParseIndexResponse(t *testing.T) {
+	for _, tuple := range []struct {
+		body            string
+		expectedCreated bool
+		expectedResult  string
+		expectedVersion int64
+	}{
+		{ // created, newer shape
+			body:            `{"_index":"twitter","_type":"_doc","_id":"1","_version":1,"result":"created","_shards":{"total":2,"successful":1,"failed":0}}`,
+			expectedCreated: true,
+			expectedResult:  "created",
+			expectedVersion: 1,
+		},
+		{ // updated, newer shape
+			body:            `{"_index":"twitter","_type":"_doc","_id":"1","_version":2,"result":"updated"}`,
+			expectedCreated: false,
+			expectedResult:  "updated",
+			expectedVersion: 2,
+		},
+		{ // created, older shape
+			body:            `{"_index":"twitter","_type":"tweet","_id":"1","_version":1,"created":true}`,
+			expectedCreated: true,
+			expectedResult:  "created",
+			expectedVersion: 1,
+		},
+		{ // updated, older shape
+			body:            `{"_index":"twitter","_type":"tweet","_id":"1","_version":2,"created":false}`,
+			expectedCreated: false,
+			expectedResult:  "updated",
+			expectedVersion: 2,
+		},
+	} {
+		response, err := es.ParseIndexResponse(strings.NewReader(tuple.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, got := "1", response.ID; expected != got {
+			t.Errorf("%s: expected id = %q; got %q", tuple.body, expected, got)
+		}
+
+		if expected, got := tuple.expectedCreated, response.Created; expected != got {
+			t.Errorf("%s: expected created = %v; got %v", tuple.body, expected, got)
+		}
+
+		if expected, got := tuple.expectedResult, response.Result; expected != got {
+			t.Errorf("%s: expected result = %q; got %q", tuple.body, expected, got)
+		}
+
+		if expected, got := tuple.expectedVersion, response.Version; expected != got {
+			t.Errorf("%s: expected version = %d; got %d", tuple.body, expected, got)
+		}
+	}
+}
+
+func TestParseBulkResponse(t *testing.T) {
+	body := `{"took":30,"errors":true,"items":[
+		{"index":{"_index":"twitter","_type":"tweet","_id":"1","_version":1,"status":201}},
+		{"create":{"_index":"twitter","_type":"tweet","_id":"2","status":409,"error":{"type":"version_conflict_engine_exception","reason":"[2]: version conflict, document already exists"}}},
+		{"delete":{"_index":"twitter","_type":"tweet","_id":"3","_version":2,"status":200,"found":true}}
+	]}`
+
+	response, err := es.ParseBulkResponse(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !response.Errors {
+		t.Errorf("expected errors = true")
+	}
+
+	if expected, got := 3, len(response.Items); expected != got {
+		t.Fatalf("expected %d item(s); got %d", expected, got)
+	}
+
+	for i, expected := range []struct {
+		id     string
+		status int
+		failed bool
+	}{
+		{"1", 201, false},
+		{"2", 409, true},
+		{"3", 200, false},
+	} {
+		item := response.Items[i]
+
+		if got := item.ID; expected.id != got {
+			t.Errorf("item %d: expected id = %q; got %q", i, expected.id, got)
+		}
+
+		if got := item.Status; expected.status != got {
+			t.Errorf("item %d: expected status = %d; got %d", i, expected.status, got)
+		}
+
+		if got := item.Error != ""; expected.failed != got {
+			t.Errorf("item %d: expected failed = %v; got error %q", i, expected.failed, item.Error)
+		}
+	}
+}
+
+func TestBulkParamsSetRefresh(t *testing.T) {
+	bulk := es.NewBulkIndex(es.IndexRequest{
+		es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"},
+		map[string]string{"user": "kimchy"},
+	})
+
+	if err := bulk.Params.SetRefresh("immediately"); err == nil {
+		t.Errorf("expected an error for refresh = immediately")
+	}
+
+	if err := bulk.Params.SetRefresh("wait_for"); err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := bulk.Request(&url.URL{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "refresh=wait_for", request.URL.RawQuery; expected != got {
+		t.Errorf("expected query = %q; got %q", expected, got)
+	}
+}
+
+func TestTypelessPaths(t *testing.T) {
+	typeless := es.IndexParams{Index: "twitter", Id: "1"}
+
+	for _, tuple := range []struct {
+		f        es.Fireable
+		expected string
+	}{
+		{es.IndexRequest{typeless, nil}, "PUT /twitter/_doc/1"},
+		{es.IndexRequest{es.IndexParams{Index: "twitter"}, nil}, "POST /twitter/_doc"},
+		{es.CreateRequest{typeless, nil}, "PUT /twitter/_create/1"},
+		{es.UpdateRequest{Params: typeless}, "POST /twitter/_update/1"},
+		{es.DeleteRequest{typeless}, "DELETE /twitter/_doc/1"},
+		{es.GetRequest{Index: "twitter", Id: "1"}, "GET /twitter/_doc/1"},
+		{es.SearchRequest{Params: es.SearchParams{Indices: []string{"twitter"}}}, "GET /twitter/_search"},
+		{es.CountRequest{Indices: []string{"twitter"}}, "GET /twitter/_count"},
+		{es.MultiGetRequest{Index: "twitter"}, "POST /twitter/_mget"},
+		{es.PutMappingRequest{Index: "twitter"}, "PUT /twitter/_mapping"},
+	} {
+		request, err := tuple.f.Request(&url.URL{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := request.Method + " " + request.URL.Path
+		if tuple.expected != got {
+			t.Errorf("expected %q; got %q", tuple.expected, got)
+		}
+
+		if strings.Contains(request.URL.Path, "//") || strings.HasSuffix(request.URL.Path, "/") {
+			t.Errorf("%q: unexpected empty path segment", request.URL.Path)
+		}
+	}
+}
+
+func TestTypelessBulkHeader(t *testing.T) {
+	request, err := es.NewBulkIndex(es.IndexRequest{
+		es.IndexParams{Index: "twitter", Id: "1"},
+		map[string]string{"user": "kimchy"},
+	}).Request(&url.URL{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "{\"index\":{\"_index\":\"twitter\",\"_id\":\"1\"}}\n{\"user\":\"kimchy\"}\n"; expected != string(got) {
+		t.Errorf("Body: expected:\n---\n%s\n---\ngot:\n---\n%s\n---\n", expected, got)
+	}
+}
+
+func TestWithRouting(t *testing.T) {
+	params := es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"}
+	source := map[string]string{"user": "kimchy"}
+
+	for _, tuple := range []struct {
+		f        es.Fireable
+		expected string
+	}{
+		{es.IndexRequest{params, source}.WithRouting("kimchy"), "kimchy"},
+		{es.CreateRequest{params, source}.WithRouting("kimchy"), "kimchy"},
+		{es.UpdateRequest{Params: params, Source: source}.WithRouting("kimchy"), "kimchy"},
+		{es.DeleteRequest{params}.WithRouting("kimchy"), "kimchy"},
+		{es.GetRequest{Index: "twitter", Id: "1"}.WithRouting("kimchy"), "kimchy"},
+		{es.GetRequest{Index: "twitter", Id: "1", Params: es.GetParams{Routing: "own"}}.WithRouting("kimchy"), "own"},
+		{es.IndexRequest{es.IndexParams{Index: "twitter", Id: "1", Routing: "own"}, source}.WithRouting("kimchy"), "own"},
+	} {
+		request, err := tuple.f.Request(&url.URL{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, got := tuple.expected, request.URL.Query().Get("routing"); expected != got {
+			t.Errorf("%s: expected routing = %q; got %q", request.URL.Path, expected, got)
+		}
+	}
+
+	request, err := es.NewBulk(
+		es.IndexRequest{params, source}.WithRouting("kimchy"),
+		es.DeleteRequest{params}.WithRouting("kimchy"),
+	).Request(&url.URL{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"index":{"_index":"twitter","_type":"tweet","_id":"1","_routing":"kimchy"}}
+{"user":"kimchy"}
+{"delete":{"_index":"twitter","_type":"tweet","_id":"1","_routing":"kimchy"}}
+`
+
+	got, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected != string(got) {
+		t.Errorf("Body: expected:\n---\n%s\n---\ngot:\n---\n%s\n---\n", expected, got)
+	}
+}
+
+func TestIndexParamsSetVersion(t *testing.T) {
+	p := es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"}
+	if err := p.SetVersion(5, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := es.IndexRequest{p, map[string]string{"user": "kimchy"}}.Request(&url.URL{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "version=5", request.URL.RawQuery; expected != got {
+		t.Errorf("expected query = %q; got %q", expected, got)
+	}
+
+	if err := p.SetVersion(0, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "", p.Values().Encode(); expected != got {
+		t.Errorf("expected no version for 0; got %q", got)
+	}
+
+	if err := p.SetVersion(7, "bogus"); err == nil {
+		t.Errorf("expected an error for version_type bogus")
+	}
+
+	if err := p.SetVersion(7, "external"); err != nil {
+		t.Fatal(err)
+	}
+
+	bulk, err := es.NewBulk(es.DeleteRequest{p}).Request(&url.URL{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ioutil.ReadAll(bulk.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"delete":{"_index":"twitter","_type":"tweet","_id":"1","_version":"7","_version_type":"external"}}` + "\n"
+	if expected != string(got) {
+		t.Errorf("expected bulk header %s; got %s", expected, got)
+	}
+}
+
+func TestIndexParamsSetIfSeqNo(t *testing.T) {
+	p := es.IndexParams{Index: "twitter", Id: "1"}
+	p.SetIfSeqNo(0, 1)
+
+	request, err := es.IndexRequest{p, map[string]string{"user": "kimchy"}}.Request(&url.URL{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "if_primary_term=1&if_seq_no=0", request.URL.RawQuery; expected != got {
+		t.Errorf("expected query = %q; got %q", expected, got)
+	}
+
+	for _, tuple := range []struct {
+		params   es.IndexParams
+		expected string
+	}{
+		{
+			params:   p,
+			expected: `{"delete":{"_index":"twitter","_id":"1","if_seq_no":"0","if_primary_term":"1"}}` + "\n",
+		},
+		{
+			params:   es.IndexParams{Index: "twitter", Id: "1"},
+			expected: `{"delete":{"_index":"twitter","_id":"1"}}` + "\n",
+		},
+	} {
+		bulk, err := es.NewBulk(es.DeleteRequest{tuple.params}).Request(&url.URL{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ioutil.ReadAll(bulk.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tuple.expected != string(got) {
+			t.Errorf("expected bulk header %s; got %s", tuple.expected, got)
+		}
+	}
+
+	for _, params := range []es.IndexParams{
+		{Index: "twitter", Id: "1", IfSeqNo: "3"},
+		{Index: "twitter", Id: "1", IfPrimaryTerm: "1"},
+		{Index: "twitter", Id: "1", IfSeqNo: "three", IfPrimaryTerm: "1"},
+	} {
+		if _, err := (es.DeleteRequest{params}).Request(&url.URL{}); err == nil {
+			t.Errorf("%+v: expected an error", params)
+		}
+	}
+}
+
+func TestUpdateRequestBody(t *testing.T) {
+	params := es.IndexParams{Index: "twitter", Id: "1"}
+
+	for _, tuple := range []struct {
+		r        es.UpdateRequest
+		expected string
+	}{
+		{
+			r: es.UpdateRequest{
+				Params:       params,
+				Script:       "ctx._source.counter += params.count",
+				ScriptParams: map[string]interface{}{"count": 4},
+				Upsert:       map[string]int{"counter": 1},
+			},
+			expected: `{"script":{"params":{"count":4},"source":"ctx._source.counter += params.count"},"upsert":{"counter":1}}`,
+		},
+		{
+			r: es.UpdateRequest{
+				Params:      params,
+				Source:      map[string]string{"user": "kimchy"},
+				DocAsUpsert: true,
+			},
+			expected: `{"doc":{"user":"kimchy"},"doc_as_upsert":true}`,
+		},
+		{
+			r: es.UpdateRequest{
+				Params:      params,
+				Source:      strings.NewReader(`{"user":"kimchy"}`),
+				DocAsUpsert: true,
+			},
+			expected: `{"doc":{"user":"kimchy"},"doc_as_upsert":true}`,
+		},
+		{
+			r: es.UpdateRequest{
+				Params: params,
+				Source: map[string]interface{}{"doc": map[string]string{"user": "kimchy"}},
+			},
+			expected: `{"doc":{"user":"kimchy"}}`,
+		},
+	} {
+		if expected, got := "POST /twitter/_update/1\n"+tuple.expected+"\n", requestBytes(t, tuple.r); expected != got {
+			t.Errorf("expected %q; got %q", expected, got)
+		}
+	}
+
+	bulk, err := es.NewBulk(es.UpdateRequest{
+		Params:       params,
+		Script:       "ctx._source.counter++",
+		ScriptParams: map[string]interface{}{},
+	}).Request(&url.URL{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ioutil.ReadAll(bulk.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"update":{"_index":"twitter","_id":"1"}}` + "\n" + `{"script":{"source":"ctx._source.counter++"}}` + "\n"
+	if expected != string(got) {
+		t.Errorf("expected bulk body %s; got %s", expected, got)
+	}
+
+	if _, err := (es.UpdateRequest{
+		Params: params,
+		Source: map[string]string{"user": "kimchy"},
+		Script: "ctx._source.counter++",
+	}).Request(&url.URL{}); err == nil {
+		t.Errorf("expected an error for both a script and a doc")
+	}
+}
+
+func TestBulkDelete(t *testing.T) {
+	var requests int
+	var query, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		query = r.URL.RawQuery
+		buf, _ := ioutil.ReadAll(r.Body)
+		body = string(buf)
+		w.Write([]byte(`{"took":2,"errors":false,"items":[
+			{"delete":{"_index":"twitter","_id":"1","_version":2,"result":"deleted","status":200}},
+			{"delete":{"_index":"twitter","_id":"2","_version":1,"result":"not_found","status":404}}
+		]}`))
+	}))
+	defer server.Close()
+
+	node := es.NewNode(server.URL, time.Second)
+
+	response, err := es.BulkDelete(node, "twitter", "", []string{"1", "2"}, es.BulkParams{Refresh: "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "refresh=true", query; expected != got {
+		t.Errorf("expected query = %q; got %q", expected, got)
+	}
+
+	expected := `{"delete":{"_index":"twitter","_id":"1"}}` + "\n" + `{"delete":{"_index":"twitter","_id":"2"}}` + "\n"
+	if expected != body {
+		t.Errorf("expected body %s; got %s", expected, body)
+	}
+
+	if expected, got := 2, len(response.Items); expected != got {
+		t.Fatalf("expected %d item(s); got %d", expected, got)
+	}
+
+	for i, expected := range []string{"deleted", "not_found"} {
+		if got := response.Items[i].Result; expected != got {
+			t.Errorf("item %d: expected result %q; got %q", i, expected, got)
+		}
+	}
+
+	if _, err := es.BulkDelete(node, "twitter", "", nil, es.BulkParams{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := 1, requests; expected != got {
+		t.Errorf("expected no request for no ids; got %d request(s)", got-expected)
+	}
+}
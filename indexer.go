@@ -0,0 +1,242 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// A BulkIndexer batches individual operations into bulk requests, which it
+// fires whenever flushCount operations are queued, or before the bulk would
+// grow past flushBytes. Operations are encoded as they're added, so the
+// memory held by a BulkIndexer is bounded by flushBytes, and a bulk is never
+// larger, unless a single operation is. Zero disables either threshold.
+//
+// If a bulk can't be sent, or ElasticSearch is overloaded, ie. responds 429
+// or 5xx, its operations stay queued, and are sent again with the next flush;
+// until then, the bulk may grow past flushBytes. Any other failure, eg. a 400
+// or 413, drops them, with a *DroppedBulkError. Operations which fail
+// individually are reported, but not retried.
+type BulkIndexer struct {
+	sync.Mutex
+	firer      Firer
+	params     BulkParams
+	flushCount int
+	flushBytes int
+
+	buf *bytes.Buffer // encoded operations, not yet flushed
+	n   int           // number of operations in buf
+
+	// When adaptive, flushCount is adjusted after each flush, to keep the
+	// took time of each bulk near target.
+	adaptive bool
+	target   time.Duration
+	min, max int
+}
+
+// NewBulkIndexer returns a BulkIndexer which fires bulks with the given params
+// against f, eg. a Node or Cluster.
+func NewBulkIndexer(f Firer, params BulkParams, flushCount, flushBytes int) *BulkIndexer {
+	return &BulkIndexer{
+		firer:      f,
+		params:     params,
+		flushCount: flushCount,
+		flushBytes: flushBytes,
+		buf:        new(bytes.Buffer),
+	}
+}
+
+// Adapt makes the BulkIndexer adjust its flushCount, within [min, max], to
+// keep each bulk's took time under target: it's halved after a bulk takes
+// longer than target, and doubled after one takes less than half of it.
+func (b *BulkIndexer) Adapt(target time.Duration, min, max int) {
+	b.Lock()
+	defer b.Unlock()
+	b.adaptive, b.target, b.min, b.max = true, target, min, max
+	b.flushCount = clamp(b.flushCount, min, max)
+}
+
+// BatchSize returns the number of operations which trigger a flush, which
+// changes over time if the BulkIndexer is adaptive.
+func (b *BulkIndexer) BatchSize() int {
+	b.Lock()
+	defer b.Unlock()
+	return b.flushCount
+}
+
+// adapt adjusts flushCount, if the BulkIndexer is adaptive, after a bulk of n
+// operations took the given time.
+func (b *BulkIndexer) adapt(n int, took time.Duration) {
+	switch {
+	case !b.adaptive:
+	case took > b.target:
+		b.flushCount = clamp(n/2, b.min, b.max)
+	case took < b.target/2 && n >= b.flushCount:
+		b.flushCount = clamp(2*b.flushCount, b.min, b.max)
+	}
+}
+
+func clamp(n, min, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// Add queues the operation, flushing first if it would take the bulk past
+// flushBytes, and afterwards if either threshold has been reached.
+//
+// Unless op can't be encoded, Add takes ownership of it: it's queued even if
+// a flush fails, so don't Add it again on error. The error describes the
+// flush: a transient one leaves every operation queued for the next flush, a
+// *DroppedBulkError gives the operations dropped, and a *BulkItemsError those
+// which were sent but failed.
+func (b *BulkIndexer) Add(op BulkIndexable) error {
+	b.Lock()
+	defer b.Unlock()
+
+	encoded := new(bytes.Buffer)
+	enc := json.NewEncoder(encoded)
+
+	if err := op.EncodeBulkHeader(enc); err != nil {
+		return err
+	}
+
+	if err := op.EncodeSource(enc); err != nil {
+		return err
+	}
+
+	var err error
+	if b.flushBytes > 0 && b.n > 0 && b.buf.Len()+encoded.Len() > b.flushBytes {
+		err = b.flush()
+	}
+
+	b.buf.Write(encoded.Bytes())
+	b.n++
+
+	if err != nil {
+		return err
+	}
+
+	if (b.flushCount > 0 && b.n >= b.flushCount) || (b.flushBytes > 0 && b.buf.Len() >= b.flushBytes) {
+		return b.flush()
+	}
+
+	return nil
+}
+
+// Flush fires the queued operations, if any, as a single bulk. It returns an
+// error if the bulk, or any operation in it, failed; see Add.
+func (b *BulkIndexer) Flush() error {
+	b.Lock()
+	defer b.Unlock()
+	return b.flush()
+}
+
+// Close flushes any remaining operations.
+func (b *BulkIndexer) Close() error {
+	return b.Flush()
+}
+
+func (b *BulkIndexer) flush() error {
+	if b.n == 0 {
+		return nil
+	}
+
+	body, n := b.buf.Bytes(), b.n
+
+	var response BulkResponse
+	r, err := b.firer.Fire(encodedBulk{b.params, body}, &response)
+	if err == nil && r.StatusCode >= 300 {
+		err = fmt.Errorf("status %d", r.StatusCode)
+	}
+	if isTransient(err) {
+		return err // still queued, for the next flush
+	}
+
+	var dropped []byte
+	if err != nil {
+		dropped = append(dropped, body...) // before the buffer is reused
+	}
+
+	b.buf.Reset()
+	b.n = 0
+
+	if err != nil {
+		return &DroppedBulkError{N: n, Body: dropped, Err: err}
+	}
+
+	b.adapt(n, time.Duration(response.Took)*time.Millisecond)
+
+	var failed []BulkItemResponse
+	for _, item := range response.Items {
+		if item.Error != "" {
+			failed = append(failed, item)
+		}
+	}
+
+	if len(failed) > 0 {
+		return &BulkItemsError{N: n, Failed: failed}
+	}
+
+	return nil
+}
+
+// isTransient reports whether a bulk which failed with err may succeed if
+// it's sent again: it couldn't be sent, or ElasticSearch was overloaded.
+func isTransient(err error) bool {
+	var e *ESError
+	if errors.As(err, &e) {
+		return e.Status == http.StatusTooManyRequests || e.Status >= 500
+	}
+	return isDialError(err)
+}
+
+// A DroppedBulkError is returned by a BulkIndexer when a bulk is rejected
+// outright, eg. with a 400 or 413 status. Its operations are dropped from the
+// queue; Body holds them, encoded as in the bulk, to inspect or resend.
+type DroppedBulkError struct {
+	N    int // number of operations
+	Body []byte
+	Err  error
+}
+
+func (e *DroppedBulkError) Error() string {
+	return fmt.Sprintf("bulk of %d operation(s) dropped: %s", e.N, e.Err)
+}
+
+func (e *DroppedBulkError) Unwrap() error {
+	return e.Err
+}
+
+// A BulkItemsError is returned by a BulkIndexer when a bulk is accepted, but
+// some of its operations fail. They aren't retried.
+type BulkItemsError struct {
+	N      int                // number of operations in the bulk
+	Failed []BulkItemResponse // in the order they were added
+}
+
+func (e *BulkItemsError) Error() string {
+	return fmt.Sprintf("bulk of %d operation(s): %d failed, eg. %s", e.N, len(e.Failed), e.Failed[0].Error)
+}
+
+// encodedBulk is a bulk request whose operations are already encoded.
+type encodedBulk struct {
+	params BulkParams
+	body   []byte
+}
+
+func (r encodedBulk) Request(uri *url.URL) (*http.Request, error) {
+	uri.Path = "/_bulk"
+	uri.RawQuery = r.params.Values().Encode()
+
+	return http.NewRequest("PUT", uri.String(), bytes.NewReader(r.body))
+}
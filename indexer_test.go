@@ -0,0 +1,322 @@
+package elasticsearch_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	es "github.com/peterbourgon/elasticsearch"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// bulkServer records the number of operations in each bulk it receives.
+func bulkServer(bulks *[]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		*bulks = append(*bulks, bytes.Count(body, []byte("\n"))/2)
+		w.Write([]byte(`{"took":1,"items":[]}`))
+	}))
+}
+
+func indexOp(id string) es.IndexRequest {
+	return es.IndexRequest{
+		es.IndexParams{Index: "twitter", Type: "tweet", Id: id},
+		map[string]string{"user": "kimchy"},
+	}
+}
+
+func checkBulks(t *testing.T, when string, expected, got []int) {
+	if len(expected) != len(got) {
+		t.Fatalf("%s: expected bulks of %v; got %v", when, expected, got)
+	}
+	for i := range expected {
+		if expected[i] != got[i] {
+			t.Fatalf("%s: expected bulks of %v; got %v", when, expected, got)
+		}
+	}
+}
+
+func TestBulkIndexerFlushCount(t *testing.T) {
+	var bulks []int
+	server := bulkServer(&bulks)
+	defer server.Close()
+
+	indexer := es.NewBulkIndexer(es.NewNode(server.URL, time.Second), es.BulkParams{}, 3, 0)
+
+	for _, id := range []string{"1", "2"} {
+		if err := indexer.Add(indexOp(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkBulks(t, "before the boundary", []int{}, bulks)
+
+	if err := indexer.Add(indexOp("3")); err != nil {
+		t.Fatal(err)
+	}
+	checkBulks(t, "at the boundary", []int{3}, bulks)
+
+	if err := indexer.Add(indexOp("4")); err != nil {
+		t.Fatal(err)
+	}
+	if err := indexer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkBulks(t, "after Close", []int{3, 1}, bulks)
+}
+
+func TestBulkIndexerFlushBytes(t *testing.T) {
+	var bulks []int
+	server := bulkServer(&bulks)
+	defer server.Close()
+
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	indexOp("1").EncodeBulkHeader(enc)
+	indexOp("1").EncodeSource(enc)
+	size := buf.Len() // the same for each single-digit id
+
+	indexer := es.NewBulkIndexer(es.NewNode(server.URL, time.Second), es.BulkParams{}, 0, 2*size+size/2)
+
+	for _, id := range []string{"1", "2"} {
+		if err := indexer.Add(indexOp(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkBulks(t, "below the boundary", []int{}, bulks)
+
+	// A third operation would cross the boundary, so the first two are
+	// flushed before it's queued.
+	if err := indexer.Add(indexOp("3")); err != nil {
+		t.Fatal(err)
+	}
+	checkBulks(t, "crossing the boundary", []int{2}, bulks)
+
+	if err := indexer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	checkBulks(t, "after Flush", []int{2, 1}, bulks)
+
+	if err := indexer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	checkBulks(t, "after an empty Flush", []int{2, 1}, bulks)
+}
+
+func TestBulkIndexerFlushBytesExact(t *testing.T) {
+	var bulks []int
+	server := bulkServer(&bulks)
+	defer server.Close()
+
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	indexOp("1").EncodeBulkHeader(enc)
+	indexOp("1").EncodeSource(enc)
+
+	indexer := es.NewBulkIndexer(es.NewNode(server.URL, time.Second), es.BulkParams{}, 0, 2*buf.Len())
+
+	if err := indexer.Add(indexOp("1")); err != nil {
+		t.Fatal(err)
+	}
+	checkBulks(t, "below the boundary", []int{}, bulks)
+
+	if err := indexer.Add(indexOp("2")); err != nil {
+		t.Fatal(err)
+	}
+	checkBulks(t, "at the boundary", []int{2}, bulks)
+}
+
+func TestBulkIndexerItemErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"took":1,"items":[{"index":{"_id":"1","status":400,"error":"MapperParsingException[failed to parse]"}}]}`))
+	}))
+	defer server.Close()
+
+	indexer := es.NewBulkIndexer(es.NewNode(server.URL, time.Second), es.BulkParams{}, 1, 0)
+
+	err := indexer.Add(indexOp("1"))
+	var failed *es.BulkItemsError
+	if !errors.As(err, &failed) {
+		t.Fatalf("expected a BulkItemsError; got %v", err)
+	}
+
+	if expected, got := 1, len(failed.Failed); expected != got {
+		t.Fatalf("expected %d failed item(s); got %d", expected, got)
+	}
+
+	if expected, got := "1", failed.Failed[0].ID; expected != got {
+		t.Errorf("expected failed item %q; got %q", expected, got)
+	}
+}
+
+func TestBulkIndexerAddOwnership(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":"unavailable","status":503}`))
+			return
+		}
+		w.Write([]byte(`{"took":1,"items":[]}`))
+	}))
+	defer server.Close()
+
+	// Each operation is about 75 bytes, so the second Add flushes the first
+	// operation before queueing its own.
+	indexer := es.NewBulkIndexer(es.NewNode(server.URL, time.Second), es.BulkParams{}, 0, 100)
+	if err := indexer.Add(indexOp("1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := indexer.Add(indexOp("2")); err == nil {
+		t.Fatalf("expected the first flush to fail")
+	}
+	if err := indexer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := 2, len(bodies); expected != got {
+		t.Fatalf("expected %d bulk(s); got %d", expected, got)
+	}
+	if expected, got := 2, strings.Count(bodies[1], "\n")/2; expected != got {
+		t.Errorf("expected both operations in the retried bulk; got %d", got)
+	}
+}
+
+func TestBulkIndexerFlushFailure(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":"unavailable","status":503}`))
+			return
+		}
+		w.Write([]byte(`{"took":1,"items":[]}`))
+	}))
+	defer server.Close()
+
+	indexer := es.NewBulkIndexer(es.NewNode(server.URL, time.Second), es.BulkParams{}, 0, 0)
+	for _, id := range []string{"1", "2"} {
+		if err := indexer.Add(indexOp(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := indexer.Flush(); err == nil {
+		t.Fatalf("expected the first flush to fail")
+	}
+	if err := indexer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := 2, len(bodies); expected != got {
+		t.Fatalf("expected %d bulk(s); got %d", expected, got)
+	}
+	if bodies[0] == "" || bodies[0] != bodies[1] {
+		t.Errorf("expected the retry to send the same operations; got %q", bodies)
+	}
+
+	if err := indexer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := 2, len(bodies); expected != got {
+		t.Errorf("expected nothing left to flush; got %d bulk(s)", got)
+	}
+}
+
+func TestBulkIndexerFlushDropped(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			w.Write([]byte(`{"error":"too large","status":413}`))
+			return
+		}
+		w.Write([]byte(`{"took":1,"items":[]}`))
+	}))
+	defer server.Close()
+
+	indexer := es.NewBulkIndexer(es.NewNode(server.URL, time.Second), es.BulkParams{}, 0, 0)
+	for _, id := range []string{"1", "2"} {
+		if err := indexer.Add(indexOp(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := indexer.Flush()
+	var dropped *es.DroppedBulkError
+	if !errors.As(err, &dropped) {
+		t.Fatalf("expected a DroppedBulkError; got %v", err)
+	}
+
+	if expected, got := 2, dropped.N; expected != got {
+		t.Errorf("expected %d dropped operation(s); got %d", expected, got)
+	}
+	if expected, got := bodies[0], string(dropped.Body); expected != got {
+		t.Errorf("expected the dropped body %q; got %q", expected, got)
+	}
+	var e *es.ESError
+	if !errors.As(err, &e) || e.Status != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected the 413 ESError to be wrapped; got %v", err)
+	}
+
+	if err := indexer.Add(indexOp("3")); err != nil {
+		t.Fatal(err)
+	}
+	if err := indexer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := 2, len(bodies); expected != got {
+		t.Fatalf("expected %d bulk(s); got %d", expected, got)
+	}
+	if expected, got := 1, strings.Count(bodies[1], "\n")/2; expected != got {
+		t.Errorf("expected only the new operation to be sent; got %d", got)
+	}
+}
+
+func TestBulkIndexerAdapt(t *testing.T) {
+	took := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"took":%d,"items":[]}`, took)
+	}))
+	defer server.Close()
+
+	indexer := es.NewBulkIndexer(es.NewNode(server.URL, time.Second), es.BulkParams{}, 8, 0)
+	indexer.Adapt(100*time.Millisecond, 2, 16)
+
+	for _, step := range []struct {
+		took     int // ms
+		expected int
+	}{
+		{took: 250, expected: 4}, // slow: shrink
+		{took: 250, expected: 2}, // slow: shrink
+		{took: 250, expected: 2}, // slow, but at the minimum
+		{took: 70, expected: 2},  // near the target: hold
+		{took: 10, expected: 4},  // fast: grow
+		{took: 10, expected: 8},  // fast: grow
+		{took: 10, expected: 16}, // fast: grow
+		{took: 10, expected: 16}, // fast, but at the maximum
+		{took: 150, expected: 8}, // slow: shrink
+	} {
+		took = step.took
+		for i, n := 0, indexer.BatchSize(); i < n; i++ {
+			if err := indexer.Add(indexOp("1")); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if expected, got := step.expected, indexer.BatchSize(); expected != got {
+			t.Fatalf("after a bulk took %dms: expected batch size %d; got %d", step.took, expected, got)
+		}
+	}
+}
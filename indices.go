@@ -0,0 +1,514 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Helper function which builds the path for an API that operates on a set of
+// indices, eg. "/i1,i2/_cache/clear". No indices means all indices.
+func indicesPath(indices []string, endpoint string) string {
+	if len(indices) == 0 {
+		return fmt.Sprintf("/%s", endpoint)
+	}
+
+	return fmt.Sprintf("/%s/%s", strings.Join(indices, ","), endpoint)
+}
+
+//
+//
+//
+
+// http://www.elasticsearch.org/guide/reference/api/admin-indices-clearcache.html
+type ClearCacheParams struct {
+	Fielddata string
+	Filter    string
+	Query     string
+	Request   string
+
+	CommonParams
+}
+
+func (p ClearCacheParams) Values() url.Values {
+	return p.CommonParams.merge(values(map[string]string{
+		"fielddata": p.Fielddata,
+		"filter":    p.Filter,
+		"query":     p.Query,
+		"request":   p.Request,
+	}))
+}
+
+type ClearCacheRequest struct {
+	Indices []string
+	Params  ClearCacheParams
+}
+
+func (r ClearCacheRequest) Path() string {
+	return indicesPath(r.Indices, "_cache/clear")
+}
+
+func (r ClearCacheRequest) Request(uri *url.URL) (*http.Request, error) {
+	uri.Path = r.Path()
+	uri.RawQuery = r.Params.Values().Encode()
+
+	return http.NewRequest("POST", uri.String(), nil)
+}
+
+//
+//
+//
+
+// http://www.elasticsearch.org/guide/reference/api/admin-indices-refresh.html
+type RefreshRequest struct {
+	Indices []string
+	Params  CommonParams
+}
+
+func (r RefreshRequest) Path() string {
+	return indicesPath(r.Indices, "_refresh")
+}
+
+func (r RefreshRequest) Request(uri *url.URL) (*http.Request, error) {
+	uri.Path = r.Path()
+	uri.RawQuery = r.Params.Values().Encode()
+
+	return http.NewRequest("POST", uri.String(), nil)
+}
+
+// http://www.elasticsearch.org/guide/reference/api/admin-indices-flush.html
+type FlushParams struct {
+	Force         string
+	WaitIfOngoing string
+
+	CommonParams
+}
+
+func (p FlushParams) Values() url.Values {
+	return p.CommonParams.merge(values(map[string]string{
+		"force":           p.Force,
+		"wait_if_ongoing": p.WaitIfOngoing,
+	}))
+}
+
+type FlushRequest struct {
+	Indices []string
+	Params  FlushParams
+}
+
+func (r FlushRequest) Path() string {
+	return indicesPath(r.Indices, "_flush")
+}
+
+func (r FlushRequest) Request(uri *url.URL) (*http.Request, error) {
+	uri.Path = r.Path()
+	uri.RawQuery = r.Params.Values().Encode()
+
+	return http.NewRequest("POST", uri.String(), nil)
+}
+
+// http://www.elasticsearch.org/guide/reference/api/admin-indices-forcemerge.html
+type ForceMergeParams struct {
+	MaxNumSegments     string // eg. "1" to fully merge
+	OnlyExpungeDeletes string
+	Flush              string
+
+	CommonParams
+}
+
+func (p ForceMergeParams) Values() url.Values {
+	return p.CommonParams.merge(values(map[string]string{
+		"max_num_segments":     p.MaxNumSegments,
+		"only_expunge_deletes": p.OnlyExpungeDeletes,
+		"flush":                p.Flush,
+	}))
+}
+
+type ForceMergeRequest struct {
+	Indices []string
+	Params  ForceMergeParams
+}
+
+func (r ForceMergeRequest) Path() string {
+	return indicesPath(r.Indices, "_forcemerge")
+}
+
+func (r ForceMergeRequest) Request(uri *url.URL) (*http.Request, error) {
+	uri.Path = r.Path()
+	uri.RawQuery = r.Params.Values().Encode()
+
+	return http.NewRequest("POST", uri.String(), nil)
+}
+
+//
+//
+//
+
+// http://www.elasticsearch.org/guide/reference/api/admin-indices-stats.html
+type IndexStatsRequest struct {
+	Indices []string
+	Metrics []string // eg. docs, store, indexing, search
+	Params  CommonParams
+}
+
+func (r IndexStatsRequest) Path() string {
+	if len(r.Metrics) == 0 {
+		return indicesPath(r.Indices, "_stats")
+	}
+
+	return indicesPath(r.Indices, "_stats/"+strings.Join(r.Metrics, ","))
+}
+
+func (r IndexStatsRequest) Request(uri *url.URL) (*http.Request, error) {
+	uri.Path = r.Path()
+	uri.RawQuery = r.Params.Values().Encode()
+
+	return http.NewRequest("GET", uri.String(), nil)
+}
+
+// IndexStats holds the primaries and total statistics for an index, or for
+// all indices. The statistics themselves are left raw, as their shape depends
+// on the requested metrics.
+type IndexStats struct {
+	Primaries json.RawMessage `json:"primaries"`
+	Total     json.RawMessage `json:"total"`
+}
+
+type IndexStatsResponse struct {
+	All     IndexStats            `json:"_all"`
+	Indices map[string]IndexStats `json:"indices"`
+
+	Error  string `json:"error,omitempty"`
+	Status int    `json:"status,omitempty"`
+}
+
+//
+//
+//
+
+// http://www.elasticsearch.org/guide/reference/api/admin-indices-put-mapping.html
+type PutMappingRequest struct {
+	Index      string
+	Type       string
+	Properties map[string]interface{}
+	PathStyle  MappingPath
+	Params     CommonParams
+}
+
+// MappingPath chooses the layout of a put mapping path, which ElasticSearch
+// has changed over time. Without a Type, both are "/index/_mapping".
+type MappingPath int
+
+const (
+	// MappingPathIndexTypeMapping is "/index/type/_mapping", the default.
+	MappingPathIndexTypeMapping MappingPath = iota
+
+	// MappingPathIndexMappingType is "/index/_mapping/type", for older
+	// versions.
+	MappingPathIndexMappingType
+)
+
+// AddField returns a PutMappingRequest which adds the single field, with the
+// given mapping definition, to the type.
+func AddField(index, typ, field string, definition map[string]interface{}) PutMappingRequest {
+	return PutMappingRequest{
+		Index: index,
+		Type:  typ,
+		Properties: map[string]interface{}{
+			field: definition,
+		},
+	}
+}
+
+func (r PutMappingRequest) Path() string {
+	if r.PathStyle == MappingPathIndexMappingType {
+		return path.Join("/", r.Index, "_mapping", r.Type)
+	}
+	return path.Join("/", r.Index, r.Type, "_mapping")
+}
+
+func (r PutMappingRequest) EncodeSource(enc *json.Encoder) error {
+	return enc.Encode(map[string]interface{}{
+		"properties": r.Properties,
+	})
+}
+
+func (r PutMappingRequest) Request(uri *url.URL) (*http.Request, error) {
+	uri.Path = r.Path()
+	uri.RawQuery = r.Params.Values().Encode()
+
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+
+	if err := r.EncodeSource(enc); err != nil {
+		return nil, err
+	}
+
+	return http.NewRequest("PUT", uri.String(), buf)
+}
+
+//
+//
+//
+
+// http://www.elasticsearch.org/guide/reference/api/admin-indices-create-index.html
+type CreateIndexRequest struct {
+	Index    string
+	Settings map[string]interface{} // eg. {"number_of_shards": 3}
+	Mappings map[string]interface{}
+	Params   CommonParams
+}
+
+func (r CreateIndexRequest) Path() string {
+	return "/" + r.Index
+}
+
+// EncodeSource encodes the settings and mappings, leaving out either if it's
+// empty.
+func (r CreateIndexRequest) EncodeSource(enc *json.Encoder) error {
+	body := map[string]interface{}{}
+	if len(r.Settings) > 0 {
+		body["settings"] = r.Settings
+	}
+	if len(r.Mappings) > 0 {
+		body["mappings"] = r.Mappings
+	}
+	return enc.Encode(body)
+}
+
+func (r CreateIndexRequest) Request(uri *url.URL) (*http.Request, error) {
+	if r.Index == "" {
+		return nil, fmt.Errorf("index is required")
+	}
+
+	uri.Path = r.Path()
+	uri.RawQuery = r.Params.Values().Encode()
+
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+
+	if err := r.EncodeSource(enc); err != nil {
+		return nil, err
+	}
+
+	return http.NewRequest("PUT", uri.String(), buf)
+}
+
+// http://www.elasticsearch.org/guide/reference/api/admin-indices-delete-index.html
+type DeleteIndexRequest struct {
+	Index  string
+	Params CommonParams
+}
+
+func (r DeleteIndexRequest) Path() string {
+	return "/" + r.Index
+}
+
+func (r DeleteIndexRequest) Request(uri *url.URL) (*http.Request, error) {
+	if r.Index == "" {
+		return nil, fmt.Errorf("index is required") // rather than deleting everything
+	}
+
+	uri.Path = r.Path()
+	uri.RawQuery = r.Params.Values().Encode()
+
+	return http.NewRequest("DELETE", uri.String(), nil)
+}
+
+// IndexExistsRequest checks whether an index exists. Like an ExistsRequest,
+// its response has no body; use the IndexExists method of a Node or Cluster.
+type IndexExistsRequest struct {
+	Index  string
+	Params CommonParams
+}
+
+func (r IndexExistsRequest) Path() string {
+	return "/" + r.Index
+}
+
+func (r IndexExistsRequest) Request(uri *url.URL) (*http.Request, error) {
+	if r.Index == "" {
+		return nil, fmt.Errorf("index is required")
+	}
+
+	uri.Path = r.Path()
+	uri.RawQuery = r.Params.Values().Encode()
+
+	return http.NewRequest("HEAD", uri.String(), nil)
+}
+
+//
+//
+//
+
+// http://www.elasticsearch.org/guide/reference/api/admin-indices-aliases.html
+//
+// The actions are applied atomically and in order, eg. to move an alias from
+// an old index to a new one without downtime.
+type AliasRequest struct {
+	Actions []AliasAction
+	Params  CommonParams
+}
+
+// AliasAction adds or removes an alias of an index. Filter and Routing only
+// apply to an add; see AddAlias and RemoveAlias.
+type AliasAction struct {
+	Type    string // "add" or "remove"
+	Index   string
+	Alias   string
+	Filter  SubQuery
+	Routing string
+}
+
+// AddAlias returns an AliasAction which points the alias at the index.
+func AddAlias(index, alias string) AliasAction {
+	return AliasAction{Type: "add", Index: index, Alias: alias}
+}
+
+// RemoveAlias returns an AliasAction which removes the alias from the index.
+func RemoveAlias(index, alias string) AliasAction {
+	return AliasAction{Type: "remove", Index: index, Alias: alias}
+}
+
+func (a AliasAction) MarshalJSON() ([]byte, error) {
+	if err := oneOf("alias action", a.Type, "add", "remove"); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]interface{}{
+		a.Type: struct {
+			Index   string   `json:"index"`
+			Alias   string   `json:"alias"`
+			Filter  SubQuery `json:"filter,omitempty"`
+			Routing string   `json:"routing,omitempty"`
+		}{a.Index, a.Alias, a.Filter, a.Routing},
+	})
+}
+
+func (r AliasRequest) EncodeSource(enc *json.Encoder) error {
+	return enc.Encode(map[string]interface{}{
+		"actions": r.Actions,
+	})
+}
+
+func (r AliasRequest) Request(uri *url.URL) (*http.Request, error) {
+	if len(r.Actions) == 0 {
+		return nil, fmt.Errorf("no alias actions")
+	}
+
+	uri.Path = "/_aliases"
+	uri.RawQuery = r.Params.Values().Encode()
+
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+
+	if err := r.EncodeSource(enc); err != nil {
+		return nil, err
+	}
+
+	return http.NewRequest("POST", uri.String(), buf)
+}
+
+// GetAliasRequest gets the aliases of the indices, or the indices with the
+// named aliases, or both. No indices and no names gets every alias.
+type GetAliasRequest struct {
+	Indices []string
+	Names   []string
+	Params  CommonParams
+}
+
+func (r GetAliasRequest) Path() string {
+	if len(r.Names) == 0 {
+		return indicesPath(r.Indices, "_alias")
+	}
+
+	return indicesPath(r.Indices, "_alias/"+strings.Join(r.Names, ","))
+}
+
+func (r GetAliasRequest) Request(uri *url.URL) (*http.Request, error) {
+	uri.Path = r.Path()
+	uri.RawQuery = r.Params.Values().Encode()
+
+	return http.NewRequest("GET", uri.String(), nil)
+}
+
+// GetAliasResponse maps each index to its aliases. The definition of each
+// alias, eg. its filter, is left raw.
+type GetAliasResponse map[string]struct {
+	Aliases map[string]json.RawMessage `json:"aliases"`
+}
+
+//
+//
+//
+
+// http://www.elasticsearch.org/guide/reference/api/admin-indices-analyze.html
+//
+// Either name an Analyzer, or build a custom analysis chain from a Tokenizer
+// and optional CharFilters and Filters.
+type AnalyzeRequest struct {
+	Index      string // optional, for analyzers defined on the index
+	Analyzer   string
+	Tokenizer  string
+	CharFilter []string
+	Filter     []string
+	Text       string
+	Params     CommonParams
+}
+
+func (r AnalyzeRequest) Path() string {
+	return path.Join("/", r.Index, "_analyze")
+}
+
+func (r AnalyzeRequest) EncodeSource(enc *json.Encoder) error {
+	return enc.Encode(struct {
+		Analyzer   string   `json:"analyzer,omitempty"`
+		Tokenizer  string   `json:"tokenizer,omitempty"`
+		CharFilter []string `json:"char_filter,omitempty"`
+		Filter     []string `json:"filter,omitempty"`
+		Text       string   `json:"text"`
+	}{
+		Analyzer:   r.Analyzer,
+		Tokenizer:  r.Tokenizer,
+		CharFilter: r.CharFilter,
+		Filter:     r.Filter,
+		Text:       r.Text,
+	})
+}
+
+func (r AnalyzeRequest) Request(uri *url.URL) (*http.Request, error) {
+	if r.Analyzer != "" && r.Tokenizer != "" {
+		return nil, fmt.Errorf("analyze: analyzer and tokenizer are mutually exclusive")
+	}
+
+	uri.Path = r.Path()
+	uri.RawQuery = r.Params.Values().Encode()
+
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+
+	if err := r.EncodeSource(enc); err != nil {
+		return nil, err
+	}
+
+	return http.NewRequest("POST", uri.String(), buf)
+}
+
+type AnalyzeToken struct {
+	Token       string `json:"token"`
+	StartOffset int    `json:"start_offset"`
+	EndOffset   int    `json:"end_offset"`
+	Type        string `json:"type"`
+	Position    int    `json:"position"`
+}
+
+type AnalyzeResponse struct {
+	Tokens []AnalyzeToken `json:"tokens"`
+
+	Error  string `json:"error,omitempty"`
+	Status int    `json:"status,omitempty"`
+}
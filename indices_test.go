@@ -0,0 +1,403 @@
+package elasticsearch_test
+
+import (
+	"encoding/json"
+	es "github.com/peterbourgon/elasticsearch"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestClearCacheRequestPath(t *testing.T) {
+	for _, tuple := range []struct {
+		r        es.ClearCacheRequest
+		expected string
+	}{
+		{
+			r:        es.ClearCacheRequest{},
+			expected: "/_cache/clear",
+		},
+		{
+			r:        es.ClearCacheRequest{Indices: []string{"i1"}},
+			expected: "/i1/_cache/clear",
+		},
+		{
+			r:        es.ClearCacheRequest{Indices: []string{"i1", "i2"}},
+			expected: "/i1,i2/_cache/clear",
+		},
+	} {
+		if expected, got := tuple.expected, tuple.r.Path(); expected != got {
+			t.Errorf("%v: expected '%s', got '%s'", tuple.r, expected, got)
+		}
+	}
+}
+
+func TestClearCacheRequest(t *testing.T) {
+	request, err := es.ClearCacheRequest{
+		Indices: []string{"twitter"},
+		Params: es.ClearCacheParams{
+			Fielddata: "true",
+			Query:     "true",
+		},
+	}.Request(&url.URL{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "POST", request.Method; expected != got {
+		t.Errorf("expected method = %q; got %q", expected, got)
+	}
+
+	if expected, got := "fielddata=true&query=true", request.URL.RawQuery; expected != got {
+		t.Errorf("expected query = %q; got %q", expected, got)
+	}
+}
+
+func TestIndexStatsRequestPath(t *testing.T) {
+	for _, tuple := range []struct {
+		r        es.IndexStatsRequest
+		expected string
+	}{
+		{
+			r:        es.IndexStatsRequest{},
+			expected: "/_stats",
+		},
+		{
+			r:        es.IndexStatsRequest{Indices: []string{"i1", "i2"}},
+			expected: "/i1,i2/_stats",
+		},
+		{
+			r:        es.IndexStatsRequest{Metrics: []string{"docs", "store"}},
+			expected: "/_stats/docs,store",
+		},
+		{
+			r: es.IndexStatsRequest{
+				Indices: []string{"i1"},
+				Metrics: []string{"indexing", "search"},
+			},
+			expected: "/i1/_stats/indexing,search",
+		},
+	} {
+		if expected, got := tuple.expected, tuple.r.Path(); expected != got {
+			t.Errorf("%v: expected '%s', got '%s'", tuple.r, expected, got)
+		}
+	}
+}
+
+func TestIndexStatsResponse(t *testing.T) {
+	body := `{
+		"_shards": {"total": 10, "successful": 5, "failed": 0},
+		"_all": {
+			"primaries": {"docs": {"count": 1}},
+			"total": {"docs": {"count": 2}}
+		},
+		"indices": {
+			"twitter": {
+				"primaries": {"docs": {"count": 1}},
+				"total": {"docs": {"count": 2}}
+			}
+		}
+	}`
+
+	var response es.IndexStatsResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := `{"docs": {"count": 2}}`, string(response.All.Total); expected != got {
+		t.Errorf("expected _all.total = %s; got %s", expected, got)
+	}
+
+	twitter, ok := response.Indices["twitter"]
+	if !ok {
+		t.Fatal("expected stats for index twitter")
+	}
+
+	if expected, got := `{"docs": {"count": 1}}`, string(twitter.Primaries); expected != got {
+		t.Errorf("expected twitter.primaries = %s; got %s", expected, got)
+	}
+}
+
+func TestAddField(t *testing.T) {
+	request, err := es.AddField("twitter", "tweet", "message", map[string]interface{}{
+		"type":  "string",
+		"store": "yes",
+	}).Request(&url.URL{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "PUT", request.Method; expected != got {
+		t.Errorf("expected method = %q; got %q", expected, got)
+	}
+
+	if expected, got := "/twitter/tweet/_mapping", request.URL.Path; expected != got {
+		t.Errorf("expected path = %q; got %q", expected, got)
+	}
+
+	got, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `{"properties":{"message":{"store":"yes","type":"string"}}}` + "\n"; expected != string(got) {
+		t.Errorf("expected body = %s; got %s", expected, got)
+	}
+}
+
+func TestPutMappingRequestPath(t *testing.T) {
+	properties := map[string]interface{}{"message": map[string]string{"type": "text"}}
+
+	for _, tuple := range []struct {
+		r        es.PutMappingRequest
+		expected string
+	}{
+		{
+			r:        es.PutMappingRequest{Index: "twitter", Type: "tweet", Properties: properties},
+			expected: "/twitter/tweet/_mapping",
+		},
+		{
+			r:        es.PutMappingRequest{Index: "twitter", Type: "tweet", Properties: properties, PathStyle: es.MappingPathIndexMappingType},
+			expected: "/twitter/_mapping/tweet",
+		},
+		{
+			r:        es.PutMappingRequest{Index: "twitter", Properties: properties, PathStyle: es.MappingPathIndexMappingType},
+			expected: "/twitter/_mapping",
+		},
+	} {
+		if expected, got := "PUT "+tuple.expected+"\n"+`{"properties":{"message":{"type":"text"}}}`+"\n", requestBytes(t, tuple.r); expected != got {
+			t.Errorf("expected %q; got %q", expected, got)
+		}
+	}
+}
+
+func TestAnalyzeRequest(t *testing.T) {
+	for _, tuple := range []struct {
+		r            es.AnalyzeRequest
+		expectedPath string
+		expectedBody string
+	}{
+		{
+			r:            es.AnalyzeRequest{Analyzer: "standard", Text: "Quick Fox"},
+			expectedPath: "/_analyze",
+			expectedBody: `{"analyzer":"standard","text":"Quick Fox"}`,
+		},
+		{
+			r: es.AnalyzeRequest{
+				Index:      "twitter",
+				Tokenizer:  "standard",
+				CharFilter: []string{"html_strip"},
+				Filter:     []string{"lowercase", "stop"},
+				Text:       "The <b>Quick</b> Fox",
+			},
+			expectedPath: "/twitter/_analyze",
+			expectedBody: `{"tokenizer":"standard","char_filter":["html_strip"],"filter":["lowercase","stop"],"text":"The \u003cb\u003eQuick\u003c/b\u003e Fox"}`,
+		},
+	} {
+		request, err := tuple.r.Request(&url.URL{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, got := tuple.expectedPath, request.URL.Path; expected != got {
+			t.Errorf("expected path = %q; got %q", expected, got)
+		}
+
+		got, err := ioutil.ReadAll(request.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected := tuple.expectedBody + "\n"; expected != string(got) {
+			t.Errorf("expected body = %s; got %s", expected, got)
+		}
+	}
+}
+
+func TestAnalyzeRequestAnalyzerAndTokenizer(t *testing.T) {
+	r := es.AnalyzeRequest{Analyzer: "standard", Tokenizer: "standard", Text: "x"}
+	if _, err := r.Request(&url.URL{}); err == nil {
+		t.Errorf("expected an error when both analyzer and tokenizer are set")
+	}
+}
+
+func TestCreateIndexRequest(t *testing.T) {
+	for _, tuple := range []struct {
+		r        es.CreateIndexRequest
+		expected string
+	}{
+		{
+			r: es.CreateIndexRequest{
+				Index:    "twitter",
+				Settings: map[string]interface{}{"number_of_shards": 3},
+				Mappings: map[string]interface{}{
+					"properties": map[string]interface{}{"user": map[string]string{"type": "keyword"}},
+				},
+			},
+			expected: `{"mappings":{"properties":{"user":{"type":"keyword"}}},"settings":{"number_of_shards":3}}`,
+		},
+		{
+			r:        es.CreateIndexRequest{Index: "twitter", Settings: map[string]interface{}{"number_of_replicas": 0}},
+			expected: `{"settings":{"number_of_replicas":0}}`,
+		},
+		{
+			r:        es.CreateIndexRequest{Index: "twitter"},
+			expected: `{}`,
+		},
+	} {
+		if expected, got := "PUT /twitter\n"+tuple.expected+"\n", requestBytes(t, tuple.r); expected != got {
+			t.Errorf("expected %q; got %q", expected, got)
+		}
+	}
+}
+
+func TestIndexLifecycleRequests(t *testing.T) {
+	for _, tuple := range []struct {
+		f        es.Fireable
+		expected string
+	}{
+		{es.CreateIndexRequest{Index: "twitter", Params: es.CommonParams{Pretty: "true"}}, "PUT /twitter?pretty=true"},
+		{es.DeleteIndexRequest{Index: "twitter"}, "DELETE /twitter"},
+		{es.IndexExistsRequest{Index: "twitter"}, "HEAD /twitter"},
+	} {
+		request, err := tuple.f.Request(&url.URL{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, got := tuple.expected, request.Method+" "+request.URL.String(); expected != got {
+			t.Errorf("expected %q; got %q", expected, got)
+		}
+	}
+
+	for _, f := range []es.Fireable{
+		es.CreateIndexRequest{},
+		es.DeleteIndexRequest{},
+		es.IndexExistsRequest{},
+	} {
+		if _, err := f.Request(&url.URL{}); err == nil {
+			t.Errorf("%T: expected an error without an index", f)
+		}
+	}
+}
+
+func TestIndexExists(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "HEAD" || r.URL.Path != "/twitter" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	node := es.NewNode(server.URL, time.Second)
+
+	for index, expected := range map[string]bool{"twitter": true, "blog": false} {
+		got, err := node.IndexExists(es.IndexExistsRequest{Index: index})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected != got {
+			t.Errorf("%s: expected exists = %v; got %v", index, expected, got)
+		}
+	}
+}
+
+func TestAliasRequest(t *testing.T) {
+	filtered := es.AddAlias("tweets-2", "kimchy-tweets")
+	filtered.Filter = es.Term("user", "kimchy")
+	filtered.Routing = "kimchy"
+
+	r := es.AliasRequest{
+		Actions: []es.AliasAction{
+			es.RemoveAlias("tweets-1", "tweets"),
+			es.AddAlias("tweets-2", "tweets"),
+			filtered,
+		},
+	}
+
+	expected := "POST /_aliases\n" + `{"actions":[` +
+		`{"remove":{"index":"tweets-1","alias":"tweets"}},` +
+		`{"add":{"index":"tweets-2","alias":"tweets"}},` +
+		`{"add":{"index":"tweets-2","alias":"kimchy-tweets","filter":{"term":{"user":"kimchy"}},"routing":"kimchy"}}` +
+		`]}` + "\n"
+
+	if got := requestBytes(t, r); expected != got {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+
+	for _, r := range []es.AliasRequest{
+		{},
+		{Actions: []es.AliasAction{{Type: "rename", Index: "tweets-1", Alias: "tweets"}}},
+	} {
+		if _, err := r.Request(&url.URL{}); err == nil {
+			t.Errorf("%+v: expected an error", r.Actions)
+		}
+	}
+}
+
+func TestGetAliasRequestPath(t *testing.T) {
+	for _, tuple := range []struct {
+		r        es.GetAliasRequest
+		expected string
+	}{
+		{es.GetAliasRequest{}, "/_alias"},
+		{es.GetAliasRequest{Indices: []string{"tweets-1", "tweets-2"}}, "/tweets-1,tweets-2/_alias"},
+		{es.GetAliasRequest{Names: []string{"tweets"}}, "/_alias/tweets"},
+		{es.GetAliasRequest{Indices: []string{"tweets-2"}, Names: []string{"tweets", "kimchy-tweets"}}, "/tweets-2/_alias/tweets,kimchy-tweets"},
+	} {
+		if expected, got := "GET "+tuple.expected+"\n", requestBytes(t, tuple.r); expected != got {
+			t.Errorf("expected %q; got %q", expected, got)
+		}
+	}
+}
+
+func TestGetAliasResponse(t *testing.T) {
+	body := `{
+		"tweets-2": {"aliases": {"tweets": {}, "kimchy-tweets": {"filter": {"term": {"user": "kimchy"}}}}},
+		"tweets-1": {"aliases": {}}
+	}`
+
+	var response es.GetAliasResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := 2, len(response["tweets-2"].Aliases); expected != got {
+		t.Errorf("expected %d alias(es); got %d", expected, got)
+	}
+
+	if expected, got := `{"filter": {"term": {"user": "kimchy"}}}`, string(response["tweets-2"].Aliases["kimchy-tweets"]); expected != got {
+		t.Errorf("expected %s; got %s", expected, got)
+	}
+
+	if expected, got := 0, len(response["tweets-1"].Aliases); expected != got {
+		t.Errorf("expected %d alias(es); got %d", expected, got)
+	}
+}
+
+func TestRefreshFlushForceMergeRequests(t *testing.T) {
+	for _, tuple := range []struct {
+		f        es.Fireable
+		expected string
+	}{
+		{es.RefreshRequest{}, "POST /_refresh"},
+		{es.RefreshRequest{Indices: []string{"twitter"}}, "POST /twitter/_refresh"},
+		{es.RefreshRequest{Indices: []string{"twitter", "blog"}}, "POST /twitter,blog/_refresh"},
+		{es.FlushRequest{}, "POST /_flush"},
+		{es.FlushRequest{Indices: []string{"twitter"}, Params: es.FlushParams{WaitIfOngoing: "true"}}, "POST /twitter/_flush?wait_if_ongoing=true"},
+		{es.FlushRequest{Indices: []string{"twitter", "blog"}}, "POST /twitter,blog/_flush"},
+		{es.ForceMergeRequest{}, "POST /_forcemerge"},
+		{es.ForceMergeRequest{Indices: []string{"twitter"}, Params: es.ForceMergeParams{MaxNumSegments: "1"}}, "POST /twitter/_forcemerge?max_num_segments=1"},
+		{es.ForceMergeRequest{Indices: []string{"twitter", "blog"}, Params: es.ForceMergeParams{OnlyExpungeDeletes: "true"}}, "POST /twitter,blog/_forcemerge?only_expunge_deletes=true"},
+	} {
+		if expected, got := tuple.expected+"\n", requestBytes(t, tuple.f); expected != got {
+			t.Errorf("expected %q; got %q", expected, got)
+		}
+	}
+}
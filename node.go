@@ -1,13 +1,20 @@
 package elasticsearch
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
-	"math/rand"
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -19,6 +26,93 @@ type Node struct {
 	health     Health
 	client     *http.Client // default http client
 	pingClient *http.Client // used for Ping() only
+
+	warningHandler func(string) // called for each Warning header, if set
+	verifyProduct  bool         // require X-Elastic-Product: Elasticsearch
+	maxRetries     int          // for 429 Too Many Requests responses
+	useSourceParam bool         // send GET bodies as the source param
+	strictQueries  bool         // refuse searches with expensive queries
+	gzipThreshold  int          // compress bodies of at least this many bytes
+	requestTimeout time.Duration
+	useNumber      bool   // decode numbers into interface{} as json.Number
+	defaultRefresh string // for document writes without their own
+
+	downUntil time.Time // set by a failed dial; see Cluster.FireContext
+}
+
+// An Option configures a Node. Options passed to NewCluster are applied to
+// each of its Nodes.
+type Option func(*Node)
+
+// WarningHandler returns an Option which passes every warning ElasticSearch
+// sends in a Warning response header, eg. for deprecated usage, to f.
+func WarningHandler(f func(warning string)) Option {
+	return func(n *Node) { n.warningHandler = f }
+}
+
+// VerifyProduct returns an Option which makes requests fail unless the
+// response carries an "X-Elastic-Product: Elasticsearch" header.
+func VerifyProduct() Option {
+	return func(n *Node) { n.verifyProduct = true }
+}
+
+// MaxRetries returns an Option which retries requests rejected with 429 Too
+// Many Requests up to n times, honoring the Retry-After header.
+func MaxRetries(n int) Option {
+	return func(node *Node) { node.maxRetries = n }
+}
+
+// UseSourceParam returns an Option which sends the body of GET requests as
+// the source query parameter instead, for proxies which strip GET bodies.
+func UseSourceParam() Option {
+	return func(n *Node) { n.useSourceParam = true }
+}
+
+// StrictQueries returns an Option which makes searches containing expensive
+// queries fail before they're sent, as they would on a cluster with
+// search.allow_expensive_queries set to false. See ExpensiveQueries.
+func StrictQueries() Option {
+	return func(n *Node) { n.strictQueries = true }
+}
+
+// CompressRequests returns an Option which gzips request bodies of at least
+// threshold bytes, eg. large bulks, and sets Content-Encoding: gzip.
+func CompressRequests(threshold int) Option {
+	return func(n *Node) { n.gzipThreshold = threshold }
+}
+
+// RequestTimeout returns an Option which aborts each request, including any
+// retries, if it takes longer than d. It doesn't apply to requests made with
+// a context which has its own deadline.
+func RequestTimeout(d time.Duration) Option {
+	return func(n *Node) { n.requestTimeout = d }
+}
+
+// UseNumber returns an Option which decodes numbers in responses into
+// interface{} values, eg. a map[string]interface{} or a Hit's fields, as
+// json.Number rather than float64, so large ids and counts keep their
+// precision. Typed fields like versions are int64 regardless.
+func UseNumber() Option {
+	return func(n *Node) { n.useNumber = true }
+}
+
+// DefaultRefresh returns an Option which sets the refresh policy, eg. "true"
+// or "wait_for", of document writes which don't set their own: index, create,
+// update, delete, and bulk requests. It's useful in tests, to make writes
+// immediately searchable.
+func DefaultRefresh(refresh string) Option {
+	return func(n *Node) { n.defaultRefresh = refresh }
+}
+
+// Response describes the HTTP response to a fired request, beyond its decoded
+// body.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Warnings   []string // from the Warning headers, if any
+
+	Duration time.Duration // until the response headers, including retries
+	Attempts int           // 1, plus the number of retries
 }
 
 // NewNode constructs a Node handle. The endpoint should be of the form
@@ -31,8 +125,8 @@ type Node struct {
 //
 // Regular queries are made with the default client http.Client, which has
 // no explicit timeout set in the Transport dialer.
-func NewNode(endpoint string, pingTimeout time.Duration) *Node {
-	return &Node{
+func NewNode(endpoint string, pingTimeout time.Duration, options ...Option) *Node {
+	n := &Node{
 		endpoint: endpoint,
 		health:   Yellow,
 		client: &http.Client{
@@ -46,6 +140,10 @@ func NewNode(endpoint string, pingTimeout time.Duration) *Node {
 			},
 		},
 	}
+	for _, option := range options {
+		option(n)
+	}
+	return n
 }
 
 // Ping attempts to HTTP GET a specific endpoint, parse some kind of
@@ -96,7 +194,31 @@ func (n *Node) pingAndSet() {
 	}()
 }
 
-// GetHealth returns the health of the node, for use in the Cluster's GetBest.
+// dialCooldown is how long a Cluster passes over a Node after failing to
+// connect to it, unless no other Node is available.
+const dialCooldown = 10 * time.Second
+
+// markDown makes the node cool down after a failed dial.
+func (n *Node) markDown() {
+	n.Lock()
+	defer n.Unlock()
+	n.downUntil = time.Now().Add(dialCooldown)
+}
+
+func (n *Node) coolingDown() bool {
+	n.RLock()
+	defer n.RUnlock()
+	return time.Now().Before(n.downUntil)
+}
+
+// isDialError reports whether err is a failure to connect to a node, in which
+// case the request certainly wasn't sent, and can be safely retried elsewhere.
+func isDialError(err error) bool {
+	var e *net.OpError
+	return errors.As(err, &e) && e.Op == "dial"
+}
+
+// GetHealth returns the health of the node, for use in the Cluster's candidates.
 func (n *Node) GetHealth() Health {
 	n.RLock()
 	defer n.RUnlock()
@@ -106,24 +228,496 @@ func (n *Node) GetHealth() Health {
 // Executes the Fireable f against the node and decodes the server's reply into
 // response.
 func (n *Node) Execute(f Fireable, response interface{}) error {
-	uri, err := url.Parse(n.endpoint)
+	_, err := n.Fire(f, response)
+	return err
+}
+
+// Fire executes the Fireable f against the node, decodes the server's reply
+// into v, and returns a description of the HTTP response. Responses with an
+// error status are returned along with an *ESError.
+func (n *Node) Fire(f Fireable, v interface{}) (*Response, error) {
+	return n.FireContext(context.Background(), f, v)
+}
+
+// FireContext is Fire, aborting the request if ctx is done first.
+func (n *Node) FireContext(ctx context.Context, f Fireable, v interface{}) (*Response, error) {
+	ctx, cancel := n.withTimeout(ctx)
+	defer cancel()
+
+	began := time.Now()
+	r, attempts, err := n.do(ctx, f)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	defer r.Body.Close()
+
+	response := &Response{
+		StatusCode: r.StatusCode,
+		Header:     r.Header,
+		Warnings:   warnings(r.Header),
+		Duration:   time.Since(began),
+		Attempts:   attempts,
+	}
+
+	if n.warningHandler != nil {
+		for _, warning := range response.Warnings {
+			n.warningHandler(warning)
+		}
+	}
+
+	if n.verifyProduct {
+		if product := r.Header.Get("X-Elastic-Product"); product != "Elasticsearch" {
+			return response, fmt.Errorf("unexpected X-Elastic-Product %q", product)
+		}
+	}
+
+	if r.StatusCode >= 400 {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return response, err
+		}
+		n.decode(bytes.NewReader(body), v) // as far as it goes
+
+		e := parseError(r.StatusCode, body)
+		e.Response = r
+		return response, e
+	}
+
+	return response, n.decode(r.Body, v)
+}
+
+// decode decodes the JSON in r into v, as configured by UseNumber.
+func (n *Node) decode(r io.Reader, v interface{}) error {
+	dec := json.NewDecoder(r)
+	if n.useNumber {
+		dec.UseNumber()
+	}
+	return dec.Decode(v)
+}
+
+// Exists reports whether the document exists on the node.
+func (n *Node) Exists(r ExistsRequest) (bool, error) {
+	return exists(n.Do(r))
+}
+
+// IndexExists reports whether the index exists on the node.
+func (n *Node) IndexExists(r IndexExistsRequest) (bool, error) {
+	return exists(n.Do(r))
+}
+
+// Do sends the Fireable f to the node and returns the server's raw reply,
+// whose body the caller must close. Unlike Fire, it doesn't decode the body,
+// or check the response beyond returning an *ESError for an error status.
+func (n *Node) Do(f Fireable) (*http.Response, error) {
+	return n.DoContext(context.Background(), f)
+}
+
+// DoContext is Do, aborting the request if ctx is done first.
+func (n *Node) DoContext(ctx context.Context, f Fireable) (*http.Response, error) {
+	ctx, cancel := n.withTimeout(ctx)
+
+	r, _, err := n.do(ctx, f)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	r.Body = cancelBody{r.Body, cancel}
+
+	if r.StatusCode >= 400 {
+		body, err := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		e := parseError(r.StatusCode, body)
+		e.Response = r
+		return nil, e
+	}
+
+	return r, nil
+}
+
+// withTimeout applies the node's request timeout, if any, to ctx, unless it
+// already has a deadline.
+func (n *Node) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || n.requestTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, n.requestTimeout)
+}
+
+// cancelBody cancels the context of a request once its response body is
+// closed, as the body can only be read until then.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// do sends the Fireable f to the node. Requests rejected with 429 Too Many
+// Requests are retried, up to the node's maxRetries, after the delay given by
+// the Retry-After header. It returns the number of attempts made.
+func (n *Node) do(ctx context.Context, f Fireable) (*http.Response, int, error) {
+	f = replayable(f)
+
+	if n.strictQueries {
+		if err := checkExpensiveQueries(f); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		uri, err := url.Parse(n.endpoint)
+		if err != nil {
+			return nil, attempt, err
+		}
+
+		prefix := strings.TrimRight(uri.Path, "/") // eg. behind a proxy
+		request, err := f.Request(uri)
+		if err != nil {
+			return nil, attempt, err
+		}
+
+		if prefix != "" {
+			request.URL.Path = prefix + "/" + strings.TrimLeft(request.URL.Path, "/")
+		}
+
+		if n.defaultRefresh != "" && isDocumentWrite(f) {
+			if q := request.URL.Query(); q.Get("refresh") == "" {
+				q.Set("refresh", n.defaultRefresh)
+				request.URL.RawQuery = q.Encode()
+			}
+		}
+
+		if n.useSourceParam && request.Method == "GET" {
+			if err := moveBodyToSource(request); err != nil {
+				return nil, attempt, err
+			}
+		}
+
+		if request.Body != nil && request.Header.Get("Content-Type") == "" {
+			request.Header.Set("Content-Type", "application/json")
+		}
+
+		if n.gzipThreshold > 0 && request.Body != nil {
+			if err := compressBody(request, n.gzipThreshold); err != nil {
+				return nil, attempt, err
+			}
+		}
+
+		r, err := n.client.Do(request.WithContext(ctx))
+		if err != nil {
+			return nil, attempt + 1, err
+		}
+
+		if r.StatusCode != http.StatusTooManyRequests || attempt >= n.maxRetries {
+			return r, attempt + 1, nil
+		}
+
+		io.Copy(ioutil.Discard, r.Body)
+		r.Body.Close()
+
+		select {
+		case <-retryTimer(retryAfter(r.Header, defaultRetryDelay<<uint(attempt))):
+		case <-ctx.Done():
+			return nil, attempt + 1, ctx.Err()
+		}
+	}
+}
+
+// unwrap returns the Fireable wrapped by f, if it's a replay or a tee.
+func unwrap(f Fireable) Fireable {
+	if r, ok := f.(*replay); ok {
+		f = r.Fireable
+	}
+	if t, ok := f.(tee); ok {
+		f = t.Fireable
+	}
+	return f
+}
+
+// replay builds the request for a Fireable once, and hands out copies of it,
+// so that it can be sent again on retry, or to another node, even if its body
+// can only be read once, eg. an IndexRequest with an io.Reader Source.
+type replay struct {
+	Fireable
+	request *http.Request
+}
+
+// replayable wraps f in a replay, unless it is one already.
+func replayable(f Fireable) Fireable {
+	if _, ok := f.(*replay); ok {
+		return f
+	}
+	return &replay{Fireable: f}
+}
+
+func (r *replay) Request(uri *url.URL) (*http.Request, error) {
+	if r.request == nil {
+		request, err := r.Fireable.Request(uri)
+		if err != nil {
+			return nil, err
+		}
+
+		if request.Body != nil && request.GetBody == nil {
+			body, err := ioutil.ReadAll(request.Body)
+			request.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+
+			request.ContentLength = int64(len(body))
+			request.GetBody = func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(bytes.NewReader(body)), nil
+			}
+		}
+
+		r.request = request
+	}
+
+	request := r.request.Clone(r.request.Context())
+	request.URL.Scheme, request.URL.User, request.URL.Host = uri.Scheme, uri.User, uri.Host
+	request.Host = uri.Host
+
+	if r.request.Body != nil {
+		body, err := r.request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		request.Body = body
+	}
+
+	return request, nil
+}
+
+// isDocumentWrite reports whether f indexes, updates, or deletes documents,
+// and so takes a refresh policy.
+func isDocumentWrite(f Fireable) bool {
+	switch unwrap(f).(type) {
+	case IndexRequest, CreateRequest, UpdateRequest, DeleteRequest, BulkRequest, encodedBulk:
+		return true
+	}
+	return false
+}
+
+// checkExpensiveQueries returns an error if f is a search containing any
+// expensive queries.
+func checkExpensiveQueries(f Fireable) error {
+	var queries []SubQuery
+	switch r := unwrap(f).(type) {
+	case SearchRequest:
+		queries = searchQueries(r)
+	case CountRequest:
+		queries = append(queries, r.Query)
+	case MultiSearchRequest:
+		for _, request := range r.Requests {
+			queries = append(queries, searchQueries(request)...)
+		}
+	}
+
+	for _, q := range queries {
+		expensive, err := ExpensiveQueries(q)
+		if err != nil {
+			return err
+		}
+		if len(expensive) > 0 {
+			return fmt.Errorf("expensive queries not allowed: %s", strings.Join(expensive, ", "))
+		}
+	}
+
+	return nil
+}
+
+// searchQueries returns every query in the search r: its query, post filter,
+// kNN filter, and the filters of any filter(s) aggregations.
+func searchQueries(r SearchRequest) []SubQuery {
+	queries := []SubQuery{r.Query, r.PostFilter}
+	if r.KNN != nil {
+		queries = append(queries, r.KNN.Filter)
+	}
+	if len(r.Aggs) > 0 {
+		queries = append(queries, aggQueries{r.Aggs})
+	}
+	return queries
+}
+
+// aggQueries marshals to the queries within aggregations, as an array, for
+// ExpensiveQueries: the query of each filter aggregation, those of each
+// filters aggregation, and those within any sub-aggregations.
+type aggQueries struct {
+	aggs map[string]SubQuery
+}
+
+func (a aggQueries) MarshalJSON() ([]byte, error) {
+	buf, err := json.Marshal(a.aggs)
+	if err != nil {
+		return nil, err
+	}
+
+	var aggs map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &aggs); err != nil {
+		return nil, err
+	}
+
+	queries := []json.RawMessage{}
+	for _, agg := range aggs {
+		if filter, ok := agg["filter"]; ok {
+			queries = append(queries, filter)
+		}
+
+		if filters, ok := agg["filters"]; ok {
+			var v struct {
+				Filters json.RawMessage `json:"filters"`
+			}
+			if err := json.Unmarshal(filters, &v); err != nil {
+				return nil, err
+			}
+
+			var named map[string]json.RawMessage
+			var anonymous []json.RawMessage
+			if err := json.Unmarshal(v.Filters, &named); err == nil {
+				for _, q := range named {
+					anonymous = append(anonymous, q)
+				}
+			} else if err := json.Unmarshal(v.Filters, &anonymous); err != nil {
+				return nil, err
+			}
+			queries = append(queries, anonymous...)
+		}
+
+		for _, key := range []string{"aggs", "aggregations"} {
+			if sub, ok := agg[key]; ok {
+				var subAggs map[string]SubQuery
+				if err := json.Unmarshal(sub, &subAggs); err != nil {
+					return nil, err
+				}
+				buf, err := json.Marshal(aggQueries{subAggs})
+				if err != nil {
+					return nil, err
+				}
+				queries = append(queries, buf)
+			}
+		}
 	}
 
-	request, err := f.Request(uri)
+	return json.Marshal(queries)
+}
+
+// compressBody gzips the body of the request, if it's at least threshold
+// bytes.
+func compressBody(request *http.Request, threshold int) error {
+	body, err := ioutil.ReadAll(request.Body)
+	request.Body.Close()
 	if err != nil {
 		return err
 	}
 
-	r, err := n.client.Do(request)
+	if len(body) < threshold {
+		setBody(request, body)
+		return nil
+	}
+
+	buf := new(bytes.Buffer)
+	w := gzip.NewWriter(buf)
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	setBody(request, buf.Bytes())
+	request.Header.Set("Content-Encoding", "gzip")
+	return nil
+}
+
+// setBody makes body the body of the request, which GetBody returns afresh,
+// eg. when the transport retries the request, or follows a redirect.
+func setBody(request *http.Request, body []byte) {
+	request.Body, request.ContentLength = ioutil.NopCloser(bytes.NewReader(body)), int64(len(body))
+	request.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+}
+
+// moveBodyToSource moves the body of the request, if any, into its source
+// query parameter.
+func moveBodyToSource(request *http.Request) error {
+	if request.Body == nil {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	request.Body.Close()
 	if err != nil {
 		return err
 	}
 
-	defer r.Body.Close()
+	request.Body, request.ContentLength, request.GetBody = nil, 0, nil
+	if len(body) == 0 {
+		return nil
+	}
+
+	q := request.URL.Query()
+	q.Set("source", string(bytes.TrimSpace(body)))
+	q.Set("source_content_type", "application/json")
+	request.URL.RawQuery = q.Encode()
+	return nil
+}
+
+// defaultRetryDelay is the delay before the first retry of a 429 response
+// without a Retry-After header. It doubles with each attempt.
+const defaultRetryDelay = 100 * time.Millisecond
 
-	return json.NewDecoder(r.Body).Decode(response)
+// retryTimer returns a channel which receives once a retry's delay is over.
+// It's a variable, so tests can skip the wait.
+var retryTimer = time.After
+
+// retryAfter returns the delay requested by the Retry-After header, which is
+// either a number of seconds or an HTTP date, or d if there's no such header.
+func retryAfter(h http.Header, d time.Duration) time.Duration {
+	value := h.Get("Retry-After")
+	if value == "" {
+		return d
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if delay := t.Sub(time.Now()); delay > 0 {
+			return delay
+		}
+		return 0
+	}
+
+	return d
+}
+
+// warnings extracts the text of each Warning header, which ElasticSearch
+// formats as `299 Elasticsearch-7.10.0 "text" "date"`.
+func warnings(h http.Header) []string {
+	var a []string
+	for _, warning := range h["Warning"] {
+		if i := strings.Index(warning, `"`); i >= 0 {
+			if text, err := strconv.QuotedPrefix(warning[i:]); err == nil {
+				if unquoted, err := strconv.Unquote(text); err == nil {
+					warning = unquoted
+				}
+			}
+		}
+		a = append(a, warning)
+	}
+	return a
 }
 
 //
@@ -144,29 +738,39 @@ func (n Nodes) pingAll() {
 	}
 }
 
-// GetBest returns the "best" Node, as decided by each Node's health.
-// It's possible that no Node will be healthy enough to be returned.
-// In that case, GetBest returns an error, and processing cannot continue.
-func (n Nodes) getBest() (*Node, error) {
-	green, yellow := []*Node{}, []*Node{}
+// candidates returns the Nodes to try a request against, in order: Green
+// nodes, then Yellow ones, then those cooling down after a failed dial. Each
+// group is rotated by next, so that successive requests are spread round-robin
+// across the nodes. It's possible that no Node will be healthy enough to be
+// returned. In that case, candidates returns an error, and processing cannot
+// continue.
+func (n Nodes) candidates(next uint64) ([]*Node, error) {
+	green, yellow, down := []*Node{}, []*Node{}, []*Node{}
 	for _, node := range n {
-		switch node.GetHealth() {
-		case Green:
+		health := node.GetHealth()
+		switch {
+		case health == Red:
+		case node.coolingDown():
+			down = append(down, node)
+		case health == Green:
 			green = append(green, node)
-		case Yellow:
+		case health == Yellow:
 			yellow = append(yellow, node)
 		}
 	}
 
-	if len(green) > 0 {
-		return green[rand.Intn(len(green))], nil
+	a := []*Node{}
+	for _, group := range [][]*Node{green, yellow, down} {
+		for i := range group {
+			a = append(a, group[(uint64(i)+next)%uint64(len(group))])
+		}
 	}
 
-	if len(yellow) > 0 {
-		return yellow[rand.Intn(len(yellow))], nil
+	if len(a) == 0 {
+		return nil, fmt.Errorf("no healthy nodes available")
 	}
 
-	return nil, fmt.Errorf("no healthy nodes available")
+	return a, nil
 }
 
 //
@@ -0,0 +1,123 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNodeRetryAfter(t *testing.T) {
+	var delays []time.Duration
+	defer func(f func(time.Duration) <-chan time.Time) { retryTimer = f }(retryTimer)
+	retryTimer = func(d time.Duration) <-chan time.Time {
+		delays = append(delays, d)
+		return time.After(0)
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"_id":"1","_version":1}`))
+	}))
+	defer server.Close()
+
+	node := NewNode(server.URL, time.Second, MaxRetries(1))
+	request := IndexRequest{IndexParams{Index: "twitter", Type: "tweet", Id: "1"}, nil}
+
+	response, err := node.Fire(request, &IndexResponse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := http.StatusOK, response.StatusCode; expected != got {
+		t.Errorf("expected status = %d; got %d", expected, got)
+	}
+
+	if expected, got := 2, requests; expected != got {
+		t.Errorf("expected %d request(s); got %d", expected, got)
+	}
+
+	if expected, got := 1, len(delays); expected != got {
+		t.Fatalf("expected %d wait(s); got %d", expected, got)
+	}
+
+	if expected, got := time.Second, delays[0]; expected != got {
+		t.Errorf("expected to wait %s before retrying; waited %s", expected, got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	for _, tuple := range []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 100 * time.Millisecond},
+		{"0", 0},
+		{"3", 3 * time.Second},
+		{"soon", 100 * time.Millisecond},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0},
+	} {
+		h := http.Header{}
+		if tuple.value != "" {
+			h.Set("Retry-After", tuple.value)
+		}
+
+		if expected, got := tuple.expected, retryAfter(h, 100*time.Millisecond); expected != got {
+			t.Errorf("%q: expected a delay of %s; got %s", tuple.value, expected, got)
+		}
+	}
+}
+
+func TestCompressBodyGetBody(t *testing.T) {
+	for _, tuple := range []struct {
+		threshold  int
+		compressed bool
+	}{
+		{threshold: 1, compressed: true},
+		{threshold: 1 << 20, compressed: false},
+	} {
+		body := `{"query":{"match_all":{}}}`
+		request, err := http.NewRequest("POST", "http://localhost:9200/_search", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := compressBody(request, tuple.threshold); err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 2; i++ {
+			r, err := request.GetBody()
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf, _ := ioutil.ReadAll(r)
+
+			if expected, got := request.ContentLength, int64(len(buf)); expected != got {
+				t.Errorf("threshold %d: expected GetBody to return %d byte(s); got %d", tuple.threshold, expected, got)
+			}
+
+			if tuple.compressed {
+				z, err := gzip.NewReader(bytes.NewReader(buf))
+				if err != nil {
+					t.Fatalf("threshold %d: expected GetBody to return gzip; got %s", tuple.threshold, err)
+				}
+				buf, _ = ioutil.ReadAll(z)
+			}
+
+			if expected, got := body, string(buf); expected != got {
+				t.Errorf("threshold %d: expected body %q; got %q", tuple.threshold, expected, got)
+			}
+		}
+	}
+}
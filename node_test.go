@@ -0,0 +1,656 @@
+package elasticsearch_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	es "github.com/peterbourgon/elasticsearch"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNodeWarnings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Warning", `299 Elasticsearch-7.10.0 "[types removal] Specifying types in document index requests is deprecated" "Mon, 01 Jan 2018 00:00:00 GMT"`)
+		w.Write([]byte(`{"_id":"1","_version":1}`))
+	}))
+	defer server.Close()
+
+	var handled []string
+	node := es.NewNode(server.URL, time.Second, es.WarningHandler(func(warning string) {
+		handled = append(handled, warning)
+	}))
+
+	var indexResponse es.IndexResponse
+	response, err := node.Fire(es.IndexRequest{
+		es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"},
+		map[string]string{"user": "kimchy"},
+	}, &indexResponse)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[types removal] Specifying types in document index requests is deprecated"
+
+	if len(response.Warnings) != 1 || response.Warnings[0] != expected {
+		t.Errorf("expected warnings = [%q]; got %q", expected, response.Warnings)
+	}
+
+	if len(handled) != 1 || handled[0] != expected {
+		t.Errorf("expected handled warnings = [%q]; got %q", expected, handled)
+	}
+
+	if expected, got := int64(1), indexResponse.Version; expected != got {
+		t.Errorf("expected version = %d; got %d", expected, got)
+	}
+}
+
+func TestNodeVerifyProduct(t *testing.T) {
+	product := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if product != "" {
+			w.Header().Set("X-Elastic-Product", product)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	node := es.NewNode(server.URL, time.Second, es.VerifyProduct())
+	request := es.IndexRequest{es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"}, nil}
+
+	if _, err := node.Fire(request, &es.IndexResponse{}); err == nil {
+		t.Errorf("expected an error without X-Elastic-Product")
+	}
+
+	product = "Elasticsearch"
+	if _, err := node.Fire(request, &es.IndexResponse{}); err != nil {
+		t.Errorf("expected no error; got %s", err)
+	}
+}
+
+func TestNodeRetryReaderSource(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"_id":"1","_version":1}`))
+	}))
+	defer server.Close()
+
+	node := es.NewNode(server.URL, time.Second, es.MaxRetries(1))
+	for _, source := range []io.Reader{
+		strings.NewReader(`{"user":"kimchy"}`),
+		io.MultiReader(strings.NewReader(`{"user":"kimchy"}`)), // no GetBody
+	} {
+		bodies = nil
+		request := es.IndexRequest{es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"}, source}
+		if _, err := node.Fire(request, &es.IndexResponse{}); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, got := 2, len(bodies); expected != got {
+			t.Fatalf("expected %d request(s); got %d", expected, got)
+		}
+		if expected, got := `{"user":"kimchy"}`, bodies[1]; bodies[0] != expected || expected != got {
+			t.Errorf("expected the same body both times; got %q", bodies)
+		}
+	}
+}
+
+func TestNodeRetryRewrittenBody(t *testing.T) {
+	// Each request is refused with a 429, then redirected with a 307, which
+	// the transport follows by rewinding the body, before it succeeds.
+	var received []string
+	attempts := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				t.Errorf("%s: expected a gzip body; got %q", r.URL.Path, body)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body, _ = ioutil.ReadAll(gz)
+		}
+		received = append(received, r.Method+" "+r.URL.Query().Get("source")+string(body))
+
+		path := strings.TrimPrefix(r.URL.Path, "/moved")
+		switch attempts[path]++; attempts[path] {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			http.Redirect(w, r, "/moved"+path+"?"+r.URL.RawQuery, http.StatusTemporaryRedirect)
+		default:
+			w.Write([]byte(`{"took":1,"_id":"1","_version":1}`))
+		}
+	}))
+	defer server.Close()
+
+	node := es.NewNode(server.URL, time.Second, es.MaxRetries(1), es.UseSourceParam(), es.CompressRequests(1))
+	for _, tuple := range []struct {
+		f        es.Fireable
+		expected string
+	}{
+		{
+			f: es.IndexRequest{
+				es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"},
+				io.MultiReader(strings.NewReader(`{"user":"kimchy"}`)),
+			},
+			expected: `PUT {"user":"kimchy"}`,
+		},
+		{
+			f: es.SearchRequest{
+				Query:  es.QueryWrapper(es.Term("user", "kimchy")),
+				Method: "GET",
+			},
+			expected: `GET {"query":{"term":{"user":"kimchy"}}}`,
+		},
+	} {
+		received = nil
+		if _, err := node.Fire(tuple.f, &map[string]interface{}{}); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, got := 3, len(received); expected != got {
+			t.Fatalf("expected %d request(s); got %d", expected, got)
+		}
+		for i, got := range received {
+			if tuple.expected != got {
+				t.Errorf("request %d: expected %q; got %q", i+1, tuple.expected, got)
+			}
+		}
+	}
+}
+
+func TestNodeResponseAttempts(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= 2 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"_id":"1","_version":1}`))
+	}))
+	defer server.Close()
+
+	node := es.NewNode(server.URL, time.Second, es.MaxRetries(2))
+	request := es.IndexRequest{es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"}, nil}
+
+	response, err := node.Fire(request, &es.IndexResponse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := 3, response.Attempts; expected != got {
+		t.Errorf("expected %d attempt(s); got %d", expected, got)
+	}
+
+	if response.Duration <= 0 {
+		t.Errorf("expected a non-zero duration; got %s", response.Duration)
+	}
+}
+
+func TestClusterClose(t *testing.T) {
+	var pings int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&pings, 1)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	c := es.NewCluster([]string{server.URL}, 10*time.Millisecond, time.Second)
+	time.Sleep(50 * time.Millisecond)
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	closed := atomic.LoadInt32(&pings)
+	if closed == 0 {
+		t.Fatal("expected the cluster to ping before Close")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if got := atomic.LoadInt32(&pings); closed != got {
+		t.Errorf("expected no pings after Close; got %d", got-closed)
+	}
+}
+
+func TestNodeUseSourceParam(t *testing.T) {
+	var body []byte
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		query = r.URL.Query()
+		w.Write([]byte(`{"took":1}`))
+	}))
+	defer server.Close()
+
+	node := es.NewNode(server.URL, time.Second, es.UseSourceParam())
+	request := es.SearchRequest{
+		Params: es.SearchParams{Indices: []string{"twitter"}},
+		Query:  map[string]interface{}{"query": map[string]interface{}{"match_all": map[string]interface{}{}}},
+		Method: "GET",
+	}
+
+	if _, err := node.Fire(request, &es.SearchResponse{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(body) != 0 {
+		t.Errorf("expected no body; got %s", body)
+	}
+
+	if expected, got := `{"query":{"match_all":{}}}`, query.Get("source"); expected != got {
+		t.Errorf("expected source = %s; got %s", expected, got)
+	}
+
+	if expected, got := "application/json", query.Get("source_content_type"); expected != got {
+		t.Errorf("expected source_content_type = %q; got %q", expected, got)
+	}
+}
+
+func TestNodeStrictQueries(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"took":1}`))
+	}))
+	defer server.Close()
+
+	node := es.NewNode(server.URL, time.Second, es.StrictQueries())
+
+	wildcard := es.SearchRequest{
+		Query: es.QueryWrapper(&es.Wrapper{Name: "wildcard", Wrapped: map[string]string{"user": "ki*"}}),
+	}
+	if _, err := node.Fire(wildcard, &es.SearchResponse{}); err == nil {
+		t.Errorf("expected an error for a wildcard query")
+	}
+
+	prefix := &es.Wrapper{Name: "prefix", Wrapped: map[string]string{"user": "ki"}}
+	for name, request := range map[string]es.SearchRequest{
+		"post filter": {PostFilter: prefix},
+		"kNN filter":  {KNN: &es.KNNQuery{Field: "vector", QueryVector: []float32{1}, K: 1, NumCandidates: 1, Filter: prefix}},
+		"filter agg":  {Aggs: map[string]es.SubQuery{"ki": map[string]es.SubQuery{"filter": prefix}}},
+		"filters agg": {Aggs: map[string]es.SubQuery{"ki": map[string]es.SubQuery{
+			"filters": map[string]interface{}{"filters": map[string]es.SubQuery{"ki": prefix}},
+		}}},
+		"sub-agg": {Aggs: map[string]es.SubQuery{"users": map[string]es.SubQuery{
+			"terms": map[string]string{"field": "user"},
+			"aggs":  map[string]es.SubQuery{"ki": map[string]es.SubQuery{"filter": prefix}},
+		}}},
+	} {
+		if _, err := node.Fire(request, &es.SearchResponse{}); err == nil {
+			t.Errorf("expected an error for a prefix query in the %s", name)
+		}
+	}
+
+	aggs := es.SearchRequest{Aggs: map[string]es.SubQuery{"prefix": es.TermsAgg("prefix", 10)}}
+	if _, err := node.Fire(aggs, &es.SearchResponse{}); err != nil {
+		t.Errorf("expected no error for an aggregation on a field named prefix; got %s", err)
+	}
+
+	term := es.SearchRequest{
+		Query: es.QueryWrapper(es.TermQuery(es.TermQueryParams{Query: &es.Wrapper{Name: "user", Wrapped: "kimchy"}})),
+	}
+	if _, err := node.Fire(term, &es.SearchResponse{}); err != nil {
+		t.Errorf("expected no error for a term query; got %s", err)
+	}
+
+	if expected, got := 2, requests; expected != got {
+		t.Errorf("expected %d request(s) to reach the server; got %d", expected, got)
+	}
+}
+
+func TestNodeDo(t *testing.T) {
+	var method, path, query, contentType, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := ioutil.ReadAll(r.Body)
+		method, path, query, contentType, body = r.Method, r.URL.Path, r.URL.RawQuery, r.Header.Get("Content-Type"), string(buf)
+		w.Write([]byte(`{"_id":"1","_version":1}`))
+	}))
+	defer server.Close()
+
+	for _, endpoint := range []string{server.URL, server.URL + "/es", server.URL + "/es/"} {
+		node := es.NewNode(endpoint, time.Second)
+
+		r, err := node.Do(es.IndexRequest{
+			es.IndexParams{Index: "twitter", Type: "tweet", Id: "1", Refresh: "true"},
+			map[string]string{"user": "kimchy"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Body.Close()
+
+		expectedPath := "/twitter/tweet/1"
+		if endpoint != server.URL {
+			expectedPath = "/es" + expectedPath
+		}
+
+		if expected, got := "PUT", method; expected != got {
+			t.Errorf("%s: expected method = %q; got %q", endpoint, expected, got)
+		}
+
+		if expected, got := expectedPath, path; expected != got {
+			t.Errorf("%s: expected path = %q; got %q", endpoint, expected, got)
+		}
+
+		if expected, got := "refresh=true", query; expected != got {
+			t.Errorf("%s: expected query = %q; got %q", endpoint, expected, got)
+		}
+
+		if expected, got := "application/json", contentType; expected != got {
+			t.Errorf("%s: expected Content-Type = %q; got %q", endpoint, expected, got)
+		}
+
+		if expected, got := `{"user":"kimchy"}`+"\n", body; expected != got {
+			t.Errorf("%s: expected body = %q; got %q", endpoint, expected, got)
+		}
+	}
+}
+
+func TestNodeDoContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	node := es.NewNode(server.URL, time.Second)
+	_, err := node.DoContext(ctx, es.SearchRequest{Query: es.QueryWrapper(es.MatchAllQuery())})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected an error wrapping context.Canceled; got %v", err)
+	}
+}
+
+func TestClusterRoundRobin(t *testing.T) {
+	var a, b int32
+	serverA := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&a, 1)
+		w.Write([]byte(`{"took":1}`))
+	}))
+	defer serverA.Close()
+	serverB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&b, 1)
+		w.Write([]byte(`{"took":1}`))
+	}))
+	defer serverB.Close()
+
+	c := es.NewCluster([]string{serverA.URL, serverB.URL}, time.Hour, time.Second)
+	defer c.Close()
+
+	for i := 0; i < 4; i++ {
+		if _, err := c.Search(es.SearchRequest{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if a != 2 || b != 2 {
+		t.Errorf("expected 2 requests to each node; got %d and %d", a, b)
+	}
+}
+
+func TestClusterDialFailover(t *testing.T) {
+	var live int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&live, 1)
+		w.Write([]byte(`{"took":1}`))
+	}))
+	defer server.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	c := es.NewCluster([]string{closed.URL, server.URL}, time.Hour, time.Second)
+	defer c.Close()
+
+	for i := 0; i < 4; i++ {
+		if _, err := c.Search(es.SearchRequest{}); err != nil {
+			t.Fatalf("request %d: %s", i, err)
+		}
+	}
+
+	if expected, got := int32(4), live; expected != got {
+		t.Errorf("expected %d request(s) to the live node; got %d", expected, got)
+	}
+
+	dead := es.NewCluster([]string{closed.URL}, time.Hour, time.Second)
+	defer dead.Close()
+
+	if _, err := dead.Search(es.SearchRequest{}); err == nil {
+		t.Errorf("expected an error when no node can be dialed")
+	}
+}
+
+func TestNodeCompressRequests(t *testing.T) {
+	var encoding, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding, body = r.Header.Get("Content-Encoding"), ""
+		if encoding == "gzip" {
+			if gz, err := gzip.NewReader(r.Body); err == nil {
+				buf, _ := ioutil.ReadAll(gz)
+				body = string(buf)
+			}
+		}
+		w.Write([]byte(`{"took":1,"items":[]}`))
+	}))
+	defer server.Close()
+
+	node := es.NewNode(server.URL, time.Second, es.CompressRequests(64))
+
+	bulk := es.NewBulkIndex(
+		es.IndexRequest{es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"}, map[string]string{"user": "kimchy"}},
+		es.IndexRequest{es.IndexParams{Index: "twitter", Type: "tweet", Id: "2"}, map[string]string{"user": "bob"}},
+	)
+	if _, err := node.Fire(bulk, &es.BulkResponse{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "gzip", encoding; expected != got {
+		t.Errorf("expected Content-Encoding = %q; got %q", expected, got)
+	}
+
+	expected := `{"index":{"_index":"twitter","_type":"tweet","_id":"1"}}
+{"user":"kimchy"}
+{"index":{"_index":"twitter","_type":"tweet","_id":"2"}}
+{"user":"bob"}
+`
+	if expected != body {
+		t.Errorf("Body: expected:\n---\n%s\n---\ngot:\n---\n%s\n---\n", expected, body)
+	}
+
+	if _, err := node.Fire(es.DeleteRequest{es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"}}, &es.IndexResponse{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if encoding != "" {
+		t.Errorf("expected no Content-Encoding for a delete; got %q", encoding)
+	}
+}
+
+func TestNodeRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		w.Write([]byte(`{"took":1}`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	node := es.NewNode(server.URL, time.Second, es.RequestTimeout(50*time.Millisecond))
+
+	began := time.Now()
+	_, err := node.Fire(es.SearchRequest{}, &es.SearchResponse{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected an error wrapping context.DeadlineExceeded; got %v", err)
+	}
+
+	if elapsed := time.Since(began); elapsed > time.Second {
+		t.Errorf("expected the request to abort after about 50ms; took %s", elapsed)
+	}
+
+	// A context with its own deadline overrides the timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	time.AfterFunc(100*time.Millisecond, func() { release <- struct{}{} })
+
+	if _, err := node.FireContext(ctx, es.SearchRequest{}, &es.SearchResponse{}); err != nil {
+		t.Errorf("expected the context's deadline to override the timeout; got %v", err)
+	}
+}
+
+func TestNodeUseNumber(t *testing.T) {
+	// 2^53 + 1, which a float64 can't represent exactly.
+	body := `{"_index":"twitter","_id":"9007199254740993","_version":9007199254740993,"_seq_no":9007199254740993,"_primary_term":1}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	request := es.GetRequest{Index: "twitter", Id: "9007199254740993"}
+
+	var typed es.GetResponse
+	if _, err := es.NewNode(server.URL, time.Second).Fire(request, &typed); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := int64(9007199254740993), typed.Version; expected != got {
+		t.Errorf("expected version = %d; got %d", expected, got)
+	}
+
+	if expected, got := int64(9007199254740993), typed.SeqNo; expected != got {
+		t.Errorf("expected seq_no = %d; got %d", expected, got)
+	}
+
+	var untyped map[string]interface{}
+	if _, err := es.NewNode(server.URL, time.Second, es.UseNumber()).Fire(request, &untyped); err != nil {
+		t.Fatal(err)
+	}
+
+	version, ok := untyped["_version"].(json.Number)
+	if !ok {
+		t.Fatalf("expected a json.Number version; got %T", untyped["_version"])
+	}
+
+	if expected, got := "9007199254740993", version.String(); expected != got {
+		t.Errorf("expected version = %s; got %s", expected, got)
+	}
+
+	if n, err := version.Int64(); err != nil || n != 9007199254740993 {
+		t.Errorf("expected version = 9007199254740993; got %d (%v)", n, err)
+	}
+}
+
+func TestTee(t *testing.T) {
+	var sent []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte(`{"took":1,"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+
+	var sink bytes.Buffer
+	f := es.Tee(es.NewBulk(
+		es.IndexRequest{es.IndexParams{Index: "twitter", Id: "1"}, map[string]string{"user": "kimchy"}},
+		es.DeleteRequest{es.IndexParams{Index: "twitter", Id: "2"}},
+	), &sink)
+
+	if _, err := es.NewNode(server.URL, time.Second).Fire(f, &es.BulkResponse{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sent) == 0 {
+		t.Fatalf("expected a body to be sent")
+	}
+
+	if expected, got := string(sent), sink.String(); expected != got {
+		t.Errorf("expected the sink to get %q; got %q", expected, got)
+	}
+
+	strict := es.NewNode(server.URL, time.Second, es.StrictQueries())
+	search := es.Tee(es.SearchRequest{
+		Query: es.QueryWrapper(&es.Wrapper{Name: "wildcard", Wrapped: map[string]string{"user": "ki*"}}),
+	}, ioutil.Discard)
+
+	if _, err := strict.Fire(search, &es.SearchResponse{}); err == nil {
+		t.Errorf("expected a teed expensive query to be refused")
+	}
+}
+
+func TestNodeDefaultRefresh(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("refresh")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	node := es.NewNode(server.URL, time.Second, es.DefaultRefresh("true"))
+	params := es.IndexParams{Index: "twitter", Id: "1"}
+	source := map[string]string{"user": "kimchy"}
+
+	for _, tuple := range []struct {
+		f        es.Fireable
+		expected string
+	}{
+		{es.IndexRequest{params, source}, "true"},
+		{es.IndexRequest{es.IndexParams{Index: "twitter", Id: "1", Refresh: "wait_for"}, source}, "wait_for"},
+		{es.IndexRequest{es.IndexParams{Index: "twitter", Id: "1", Refresh: "false"}, source}, "false"},
+		{es.DeleteRequest{params}, "true"},
+		{es.UpdateRequest{Params: params, Source: map[string]interface{}{"doc": source}}, "true"},
+		{es.NewBulk(es.DeleteRequest{params}), "true"},
+		{es.BulkRequest{Params: es.BulkParams{Refresh: "false"}, Requests: []es.BulkIndexable{es.DeleteRequest{params}}}, "false"},
+		{es.SearchRequest{}, ""},
+		{es.GetRequest{Index: "twitter", Id: "1"}, ""},
+	} {
+		query = "unset"
+		if _, err := node.Fire(tuple.f, &struct{}{}); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, got := tuple.expected, query; expected != got {
+			t.Errorf("%T: expected refresh = %q; got %q", tuple.f, expected, got)
+		}
+	}
+
+	if _, err := es.NewNode(server.URL, time.Second).Fire(es.IndexRequest{params, source}, &struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "", query; expected != got {
+		t.Errorf("expected no refresh by default; got %q", got)
+	}
+}
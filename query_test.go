@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	es "github.com/peterbourgon/elasticsearch"
+	"strings"
+	"testing"
 )
 
 func marshalOrError(q es.SubQuery) string {
@@ -27,3 +29,161 @@ func ExampleBasicTermQuery() {
 	// Output:
 	// {"term":{"user":"kimchy"}}
 }
+
+func ExampleTermsLookup() {
+	q := es.TermsLookup("user", es.TermsLookupParams{
+		Index: "users",
+		Type:  "user",
+		Id:    "2",
+		Path:  "followers",
+	})
+
+	fmt.Print(marshalOrError(q))
+	// Output:
+	// {"terms":{"user":{"index":"users","type":"user","id":"2","path":"followers"}}}
+}
+
+func ExampleMinimumShouldMatch() {
+	for _, m := range []es.MinimumShouldMatch{"2", "75%"} {
+		q := es.BoolQuery(es.BoolQueryParams{
+			Should: []es.SubQuery{
+				es.TermQuery(es.TermQueryParams{Query: &es.Wrapper{Name: "tag", Wrapped: "go"}}),
+				es.TermQuery(es.TermQueryParams{Query: &es.Wrapper{Name: "tag", Wrapped: "search"}}),
+			},
+			MinimumShouldMatch: m,
+		})
+
+		fmt.Println(marshalOrError(q))
+	}
+	// Output:
+	// {"bool":{"should":[{"term":{"tag":"go"}},{"term":{"tag":"search"}}],"minimum_should_match":"2"}}
+	// {"bool":{"should":[{"term":{"tag":"go"}},{"term":{"tag":"search"}}],"minimum_should_match":"75%"}}
+}
+
+func ExampleHasField() {
+	fmt.Println(marshalOrError(es.HasField("user")))
+	fmt.Println(marshalOrError(es.MissingField("user")))
+	// Output:
+	// {"exists":{"field":"user"}}
+	// {"bool":{"must_not":{"exists":{"field":"user"}}}}
+}
+
+func ExampleBool() {
+	q := es.Bool(
+		[]es.SubQuery{es.Match("message", "search engine")},
+		[]es.SubQuery{es.Term("tag", "go"), es.Term("stars", 5)},
+		[]es.SubQuery{es.Range("date", map[string]interface{}{"lt": "2010-01-01"})},
+	)
+
+	fmt.Println(marshalOrError(q))
+	fmt.Println(marshalOrError(es.Bool([]es.SubQuery{es.Term("user", "kimchy")}, nil, nil)))
+	// Output:
+	// {"bool":{"must":[{"match":{"message":"search engine"}}],"should":[{"term":{"tag":"go"}},{"term":{"stars":5}}],"must_not":[{"range":{"date":{"lt":"2010-01-01"}}}]}}
+	// {"bool":{"must":[{"term":{"user":"kimchy"}}]}}
+}
+
+func ExampleRange() {
+	fmt.Println(marshalOrError(es.Range("age", map[string]interface{}{"gte": 10, "lte": 20, "boost": 2})))
+	// Output:
+	// {"range":{"age":{"boost":2,"gte":10,"lte":20}}}
+}
+
+func ExampleTermsAgg() {
+	fmt.Println(marshalOrError(es.TermsAgg("tags", 10)))
+	fmt.Println(marshalOrError(es.DateHistogramAgg("post_date", "1d")))
+	fmt.Println(marshalOrError(es.DateHistogramAgg("post_date", "90m")))
+	fmt.Println(marshalOrError(es.LegacyDateHistogramAgg("post_date", "1d")))
+	// Output:
+	// {"terms":{"field":"tags","size":10}}
+	// {"date_histogram":{"calendar_interval":"1d","field":"post_date"}}
+	// {"date_histogram":{"field":"post_date","fixed_interval":"90m"}}
+	// {"date_histogram":{"field":"post_date","interval":"1d"}}
+}
+
+func TestMinimumShouldMatchValidation(t *testing.T) {
+	for _, tuple := range []struct {
+		m     es.MinimumShouldMatch
+		valid bool
+	}{
+		{"75", true},
+		{"-25%", true},
+		{"3<90%", true},
+		{"2<-25% 9<-3", true},
+		{"lots", false},
+		{"2.5", false},
+		{"3<", false},
+		{"x<90%", false},
+		{"2 3<90%", false},
+	} {
+		_, err := json.Marshal(es.BoolQueryParams{MinimumShouldMatch: tuple.m})
+		if tuple.valid && err != nil {
+			t.Errorf("%q: expected no error; got %s", tuple.m, err)
+		}
+		if !tuple.valid && err == nil {
+			t.Errorf("%q: expected an error", tuple.m)
+		}
+	}
+}
+
+func TestExpensiveQueries(t *testing.T) {
+	for _, tuple := range []struct {
+		q        es.SubQuery
+		expected string
+	}{
+		{
+			q: es.QueryWrapper(es.TermQuery(es.TermQueryParams{
+				Query: &es.Wrapper{Name: "user", Wrapped: "kimchy"},
+			})),
+			expected: "",
+		},
+		{
+			q: es.QueryWrapper(es.BoolQuery(es.BoolQueryParams{
+				Must: []es.SubQuery{
+					es.TermQuery(es.TermQueryParams{Query: &es.Wrapper{Name: "user", Wrapped: "kimchy"}}),
+					&es.Wrapper{Name: "wildcard", Wrapped: map[string]string{"message": "elas*"}},
+				},
+			})),
+			expected: "wildcard",
+		},
+		{
+			q:        es.Term("prefix", "x"), // a field named like a query
+			expected: "",
+		},
+		{
+			q: es.QueryWrapper(es.ConstantScoreQuery(es.ConstantScoreQueryParams{
+				Filter: &es.Wrapper{Name: "prefix", Wrapped: map[string]string{"script": "elas"}},
+			})),
+			expected: "prefix",
+		},
+		{
+			q: es.QueryWrapper(map[string]interface{}{
+				"function_score": map[string]interface{}{
+					"query": es.Term("user", "kimchy"),
+					"functions": []interface{}{
+						map[string]interface{}{"filter": es.Term("tag", "go"), "weight": 2},
+						map[string]interface{}{"filter": &es.Wrapper{Name: "regexp", Wrapped: map[string]string{"tag": "g.*"}}, "weight": 3},
+					},
+				},
+			}),
+			expected: "regexp",
+		},
+		{
+			q: es.QueryWrapper(map[string]interface{}{
+				"filtered": map[string]interface{}{
+					"query":  map[string]interface{}{"match_all": map[string]interface{}{}},
+					"filter": &es.Wrapper{Name: "wildcard", Wrapped: map[string]string{"user": "ki*"}},
+				},
+			}),
+			expected: "wildcard",
+		},
+	} {
+		expensive, err := es.ExpensiveQueries(tuple.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, got := tuple.expected, strings.Join(expensive, ","); expected != got {
+			t.Errorf("expected expensive queries %q; got %q", expected, got)
+		}
+	}
+}
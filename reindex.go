@@ -0,0 +1,190 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+// http://www.elastic.co/guide/en/elasticsearch/reference/current/docs-reindex.html
+type ReindexParams struct {
+	Refresh           string
+	RequestsPerSecond string // throttle; "-1" disables throttling
+	Slices            string // number of parallel slices, or "auto"
+	Timeout           string
+	WaitForCompletion string
+
+	CommonParams
+}
+
+func (p ReindexParams) Values() url.Values {
+	return p.CommonParams.merge(values(map[string]string{
+		"refresh":             p.Refresh,
+		"requests_per_second": p.RequestsPerSecond,
+		"slices":              p.Slices,
+		"timeout":             p.Timeout,
+		"wait_for_completion": p.WaitForCompletion,
+	}))
+}
+
+// ReindexSource selects the documents to be copied by a ReindexRequest.
+type ReindexSource struct {
+	Indices []string `json:"index"`
+	Types   []string `json:"type,omitempty"`
+	Query   SubQuery `json:"query,omitempty"`
+	Size    int      `json:"size,omitempty"` // batch size
+}
+
+// ReindexDest describes where a ReindexRequest copies documents to.
+type ReindexDest struct {
+	Index       string `json:"index"`
+	Type        string `json:"type,omitempty"`
+	OpType      string `json:"op_type,omitempty"`
+	VersionType string `json:"version_type,omitempty"`
+}
+
+type ReindexRequest struct {
+	Params ReindexParams
+	Source ReindexSource
+	Dest   ReindexDest
+}
+
+func (r ReindexRequest) EncodeSource(enc *json.Encoder) error {
+	return enc.Encode(map[string]interface{}{
+		"source": r.Source,
+		"dest":   r.Dest,
+	})
+}
+
+func (r ReindexRequest) Request(uri *url.URL) (*http.Request, error) {
+	uri.Path = "/_reindex"
+	uri.RawQuery = r.Params.Values().Encode()
+
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+
+	if err := r.EncodeSource(enc); err != nil {
+		return nil, err
+	}
+
+	return http.NewRequest("POST", uri.String(), buf)
+}
+
+//
+//
+//
+
+// ByQueryParams are the params shared by UpdateByQueryRequest and
+// DeleteByQueryRequest.
+type ByQueryParams struct {
+	Conflicts         string // "proceed" to continue past version conflicts
+	Refresh           string
+	RequestsPerSecond string
+	Routing           string
+	Slices            string
+	WaitForCompletion string
+
+	CommonParams
+}
+
+func (p ByQueryParams) Values() url.Values {
+	return p.CommonParams.merge(values(map[string]string{
+		"conflicts":           p.Conflicts,
+		"refresh":             p.Refresh,
+		"requests_per_second": p.RequestsPerSecond,
+		"routing":             p.Routing,
+		"slices":              p.Slices,
+		"wait_for_completion": p.WaitForCompletion,
+	}))
+}
+
+// Helper function which builds a by-query request, whose body holds the
+// query and, for updates, the script. An empty body is omitted.
+func byQueryRequest(uri *url.URL, endpoint string, indices []string, p ByQueryParams, body map[string]interface{}) (*http.Request, error) {
+	uri.Path = indicesPath(indices, endpoint)
+	uri.RawQuery = p.Values().Encode()
+
+	for key, value := range body {
+		if value == nil {
+			delete(body, key)
+		}
+	}
+
+	if len(body) == 0 {
+		return http.NewRequest("POST", uri.String(), nil)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := json.NewEncoder(buf).Encode(body); err != nil {
+		return nil, err
+	}
+
+	return http.NewRequest("POST", uri.String(), buf)
+}
+
+// http://www.elastic.co/guide/en/elasticsearch/reference/current/docs-update-by-query.html
+type UpdateByQueryRequest struct {
+	Indices []string
+	Params  ByQueryParams
+	Query   SubQuery
+	Script  interface{}
+}
+
+func (r UpdateByQueryRequest) Request(uri *url.URL) (*http.Request, error) {
+	return byQueryRequest(uri, "_update_by_query", r.Indices, r.Params, map[string]interface{}{
+		"query":  r.Query,
+		"script": r.Script,
+	})
+}
+
+// http://www.elastic.co/guide/en/elasticsearch/reference/current/docs-delete-by-query.html
+type DeleteByQueryRequest struct {
+	Indices []string
+	Params  ByQueryParams
+	Query   SubQuery
+}
+
+func (r DeleteByQueryRequest) Request(uri *url.URL) (*http.Request, error) {
+	return byQueryRequest(uri, "_delete_by_query", r.Indices, r.Params, map[string]interface{}{
+		"query": r.Query,
+	})
+}
+
+//
+//
+//
+
+// RethrottleRequest changes the requests_per_second of a running reindex,
+// update-by-query, or delete-by-query task.
+type RethrottleRequest struct {
+	Endpoint          string // _reindex (default), _update_by_query, or _delete_by_query
+	TaskId            string
+	RequestsPerSecond string // "-1" disables throttling
+	Params            CommonParams
+}
+
+func (r RethrottleRequest) Path() string {
+	endpoint := r.Endpoint
+	if endpoint == "" {
+		endpoint = "_reindex"
+	}
+
+	return path.Join("/", endpoint, r.TaskId, "_rethrottle")
+}
+
+func (r RethrottleRequest) Request(uri *url.URL) (*http.Request, error) {
+	if r.Endpoint != "" {
+		if err := oneOf("endpoint", r.Endpoint, "_reindex", "_update_by_query", "_delete_by_query"); err != nil {
+			return nil, err
+		}
+	}
+
+	uri.Path = r.Path()
+	uri.RawQuery = r.Params.merge(values(map[string]string{
+		"requests_per_second": r.RequestsPerSecond,
+	})).Encode()
+
+	return http.NewRequest("POST", uri.String(), nil)
+}
@@ -0,0 +1,136 @@
+package elasticsearch_test
+
+import (
+	es "github.com/peterbourgon/elasticsearch"
+	"io/ioutil"
+	"net/url"
+	"testing"
+)
+
+func TestReindexRequest(t *testing.T) {
+	request, err := es.ReindexRequest{
+		Params: es.ReindexParams{
+			RequestsPerSecond: "500",
+			Slices:            "auto",
+		},
+		Source: es.ReindexSource{Indices: []string{"twitter"}},
+		Dest:   es.ReindexDest{Index: "new_twitter"},
+	}.Request(&url.URL{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "POST", request.Method; expected != got {
+		t.Errorf("expected method = %q; got %q", expected, got)
+	}
+
+	if expected, got := "/_reindex", request.URL.Path; expected != got {
+		t.Errorf("expected path = %q; got %q", expected, got)
+	}
+
+	q := request.URL.Query()
+
+	if expected, got := "500", q.Get("requests_per_second"); expected != got {
+		t.Errorf("expected requests_per_second = %q; got %q", expected, got)
+	}
+
+	if expected, got := "auto", q.Get("slices"); expected != got {
+		t.Errorf("expected slices = %q; got %q", expected, got)
+	}
+
+	got, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `{"dest":{"index":"new_twitter"},"source":{"index":["twitter"]}}` + "\n"; expected != string(got) {
+		t.Errorf("expected body = %s; got %s", expected, got)
+	}
+}
+
+func TestRethrottleRequest(t *testing.T) {
+	for _, tuple := range []struct {
+		r        es.RethrottleRequest
+		expected string
+	}{
+		{
+			r:        es.RethrottleRequest{TaskId: "oTUltX4IQMOUUVeiohTt8A:12345", RequestsPerSecond: "-1"},
+			expected: "/_reindex/oTUltX4IQMOUUVeiohTt8A:12345/_rethrottle",
+		},
+		{
+			r:        es.RethrottleRequest{Endpoint: "_update_by_query", TaskId: "oTUltX4IQMOUUVeiohTt8A:12345", RequestsPerSecond: "-1"},
+			expected: "/_update_by_query/oTUltX4IQMOUUVeiohTt8A:12345/_rethrottle",
+		},
+		{
+			r:        es.RethrottleRequest{Endpoint: "_delete_by_query", TaskId: "oTUltX4IQMOUUVeiohTt8A:12345", RequestsPerSecond: "-1"},
+			expected: "/_delete_by_query/oTUltX4IQMOUUVeiohTt8A:12345/_rethrottle",
+		},
+	} {
+		request, err := tuple.r.Request(&url.URL{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, got := "POST", request.Method; expected != got {
+			t.Errorf("expected method = %q; got %q", expected, got)
+		}
+
+		if expected, got := tuple.expected, request.URL.Path; expected != got {
+			t.Errorf("expected path = %q; got %q", expected, got)
+		}
+
+		if expected, got := "-1", request.URL.Query().Get("requests_per_second"); expected != got {
+			t.Errorf("expected requests_per_second = %q; got %q", expected, got)
+		}
+	}
+
+	if _, err := (es.RethrottleRequest{Endpoint: "_search", TaskId: "1"}).Request(&url.URL{}); err == nil {
+		t.Errorf("expected an error for an unknown endpoint")
+	}
+}
+
+func TestByQueryRequests(t *testing.T) {
+	params := es.ByQueryParams{Conflicts: "proceed"}
+	query := es.MatchAllQuery()
+
+	for _, tuple := range []struct {
+		f    es.Fireable
+		path string
+	}{
+		{
+			f:    es.UpdateByQueryRequest{Indices: []string{"twitter"}, Params: params, Query: query},
+			path: "/twitter/_update_by_query",
+		},
+		{
+			f:    es.DeleteByQueryRequest{Indices: []string{"twitter"}, Params: params, Query: query},
+			path: "/twitter/_delete_by_query",
+		},
+	} {
+		request, err := tuple.f.Request(&url.URL{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, got := "POST", request.Method; expected != got {
+			t.Errorf("expected method = %q; got %q", expected, got)
+		}
+
+		if expected, got := tuple.path, request.URL.Path; expected != got {
+			t.Errorf("expected path = %q; got %q", expected, got)
+		}
+
+		if expected, got := "proceed", request.URL.Query().Get("conflicts"); expected != got {
+			t.Errorf("expected conflicts = %q; got %q", expected, got)
+		}
+
+		got, err := ioutil.ReadAll(request.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected := `{"query":{"match_all":{}}}` + "\n"; expected != string(got) {
+			t.Errorf("expected body = %s; got %s", expected, got)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
@@ -23,11 +25,135 @@ func values(v map[string]string) url.Values {
 	return values
 }
 
+// Helper function which returns an error unless value is one of valid. It's
+// used by the setters of params with a constrained set of values.
+func oneOf(param, value string, valid ...string) error {
+	for _, v := range valid {
+		if value == v {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid %s %q; expected one of %s", param, value, strings.Join(valid, ", "))
+}
+
+// CommonParams are the query params accepted by every request. They're
+// embedded in each request's params, and merged into its Values. Params which
+// set their own Timeout take precedence over the common one.
+type CommonParams struct {
+	ErrorTrace string `json:"-"` // "true" for stack traces in errors
+	FilterPath string `json:"-"` // eg. "took,hits.hits._id"
+	Human      string `json:"-"` // "true" for human-readable stats
+	Pretty     string `json:"-"` // "true" for indented responses
+	Timeout    string `json:"-"`
+}
+
+func (p CommonParams) Values() url.Values {
+	return values(map[string]string{
+		"error_trace": p.ErrorTrace,
+		"filter_path": p.FilterPath,
+		"human":       p.Human,
+		"pretty":      p.Pretty,
+		"timeout":     p.Timeout,
+	})
+}
+
+// merge adds the common params to v, except where v already sets them.
+func (p CommonParams) merge(v url.Values) url.Values {
+	return mergeValues(v, p.Values(), false)
+}
+
+// Helper function which copies each key in src to dst, and returns dst. Keys
+// which dst already sets are only replaced if overwrite is true; otherwise,
+// the explicit values in dst win over the defaults in src. All of a key's
+// values are copied or replaced together.
+func mergeValues(dst, src url.Values, overwrite bool) url.Values {
+	if dst == nil {
+		dst = url.Values{}
+	}
+	for key, value := range src {
+		if _, ok := dst[key]; ok && !overwrite {
+			continue
+		}
+		dst[key] = append([]string(nil), value...)
+	}
+	return dst
+}
+
 // Fireable defines anything which can be fired against the search cluster.
 type Fireable interface {
 	Request(uri *url.URL) (*http.Request, error)
 }
 
+// CanonicalJSON returns the body of the request built by f in a canonical
+// form: each JSON value in the body is re-marshaled with sorted object keys
+// and no insignificant whitespace, one per line. It's useful for comparing
+// requests whose bodies are built in different ways.
+func CanonicalJSON(f Fireable) ([]byte, error) {
+	request, err := f.Request(&url.URL{})
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	if request.Body == nil {
+		return buf.Bytes(), nil
+	}
+	defer request.Body.Close()
+
+	dec := json.NewDecoder(request.Body)
+	dec.UseNumber()
+	enc := json.NewEncoder(buf)
+
+	for {
+		var v interface{}
+		if err := dec.Decode(&v); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if err := enc.Encode(v); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Tee returns a Fireable which builds the same requests as f, and writes the
+// body of each one to sink as it's built, eg. for an audit log. The sink gets
+// the body once per Fire, however many times the request is retried, and
+// before any compression by the Node.
+func Tee(f Fireable, sink io.Writer) Fireable {
+	return tee{f, sink}
+}
+
+type tee struct {
+	Fireable
+	sink io.Writer
+}
+
+func (t tee) Request(uri *url.URL) (*http.Request, error) {
+	request, err := t.Fireable.Request(uri)
+	if err != nil || request.Body == nil {
+		return request, err
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	request.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := t.sink.Write(body); err != nil {
+		return nil, err
+	}
+
+	setBody(request, body)
+	return request, nil
+}
+
 //
 //
 //
@@ -40,20 +166,214 @@ type SearchParams struct {
 	Routing    string `json:"routing,omitempty"`
 	Preference string `json:"preference,omitempty"`
 	SearchType string `json:"search_type,omitempty"`
+
+	// Version and SeqNoPrimaryTerm, when "true", ask for the _version and
+	// _seq_no/_primary_term of each hit, respectively.
+	Version          string `json:"-"`
+	SeqNoPrimaryTerm string `json:"-"`
+
+	// AllowPartialSearchResults set to "false" fails the search when some
+	// shards are unavailable, rather than returning partial results.
+	AllowPartialSearchResults string `json:"-"`
+
+	// BatchedReduceSize and MaxConcurrentShardRequests tune how a search
+	// across many shards is executed.
+	BatchedReduceSize          string `json:"-"`
+	MaxConcurrentShardRequests string `json:"-"`
+
+	// TypedKeys set to "true" prefixes each aggregation name in the response
+	// with its type, eg. "sterms#tags". See SearchResponse.Aggregation.
+	TypedKeys string `json:"-"`
+
+	// RestTotalHitsAsInt set to "true" makes ElasticSearch 7 return the hits
+	// total as a plain number, as older versions do.
+	RestTotalHitsAsInt string `json:"-"`
+
+	// Scroll, eg. "1m", starts a scroll, keeping the search context alive for
+	// that long. See ScrollRequest and Scroller.
+	Scroll string `json:"-"`
+
+	CommonParams
 }
 
 func (p SearchParams) Values() url.Values {
-	return values(map[string]string{
-		"timeout":     p.Timeout,
-		"routing":     p.Routing,
-		"preference":  p.Preference,
-		"search_type": p.SearchType,
-	})
+	return p.CommonParams.merge(values(map[string]string{
+		"timeout":             p.Timeout,
+		"routing":             p.Routing,
+		"preference":          p.Preference,
+		"search_type":         p.SearchType,
+		"version":             p.Version,
+		"seq_no_primary_term": p.SeqNoPrimaryTerm,
+
+		"allow_partial_search_results":  p.AllowPartialSearchResults,
+		"batched_reduce_size":           p.BatchedReduceSize,
+		"max_concurrent_shard_requests": p.MaxConcurrentShardRequests,
+		"typed_keys":                    p.TypedKeys,
+		"rest_total_hits_as_int":        p.RestTotalHitsAsInt,
+		"scroll":                        p.Scroll,
+	}))
 }
 
 type SearchRequest struct {
 	Params SearchParams
 	Query  SubQuery
+
+	// Source sets the _source of the search body, which controls the fields
+	// of each hit's source that are returned. It may be a bool, a string or
+	// []string of field patterns, or a SourceFilter. If it's nil, the whole
+	// source is returned, and false returns none.
+	Source interface{}
+
+	// Sort orders the hits, eg. by "_score", {"date": "desc"}, or a
+	// SortField, in priority order. When sorting
+	// by a field, scores aren't computed, and each hit's score is null,
+	// unless TrackScores is set.
+	Sort        []SubQuery
+	TrackScores bool
+
+	// KNN makes an approximate k-nearest neighbor search on a dense_vector
+	// field, alone or alongside the Query.
+	KNN *KNNQuery
+
+	// Suggest holds named suggesters, eg. from CompletionSuggest. Read their
+	// results from the SearchResponse's Suggest.
+	Suggest map[string]SubQuery
+
+	// Highlight asks for highlighted snippets of the matches in each hit,
+	// in the Hit's Highlight.
+	Highlight *Highlight
+
+	// Profile asks for a breakdown of the time spent on each part of the
+	// search, in the SearchResponse's Profile.
+	Profile bool
+
+	// From and Size page through the hits. Size is a pointer, as size 0 is
+	// meaningful; see Size.
+	From int
+	Size *int
+
+	// PostFilter filters the hits after the aggregations are computed, eg.
+	// for faceted navigation, where the aggregations count every facet.
+	PostFilter SubQuery
+
+	// Aggs holds named aggregations, eg. from TermsAgg. Read their results
+	// from the SearchResponse's Aggregations.
+	Aggs map[string]SubQuery
+
+	// AggsOnly sets size 0, for searches which only want the aggregations:
+	// the query still filters the documents aggregated, but no hits are
+	// fetched.
+	AggsOnly bool
+
+	// Method overrides the HTTP method. By default, searches with a body are
+	// POSTed, as some HTTP stacks drop the body of a GET, and searches without
+	// one use GET.
+	Method string
+}
+
+// Int returns a pointer to n, for the optional fields where 0 is meaningful,
+// eg. a HighlightField's NumberOfFragments.
+func Int(n int) *int {
+	return &n
+}
+
+// Size is Int, for a SearchRequest's Size.
+func Size(n int) *int {
+	return Int(n)
+}
+
+// CompletionSuggest adds a completion suggester with the given name to the
+// request, which suggests values of the completion field beginning with
+// prefix. See SearchResponse.CompletionOptions.
+func (r *SearchRequest) CompletionSuggest(name, field, prefix string) {
+	if r.Suggest == nil {
+		r.Suggest = map[string]SubQuery{}
+	}
+	r.Suggest[name] = map[string]interface{}{
+		"prefix": prefix,
+		"completion": map[string]string{
+			"field": field,
+		},
+	}
+}
+
+// KNNQuery finds the K nearest neighbors of QueryVector in Field, considering
+// NumCandidates candidates on each shard. Filter, if set, restricts the
+// documents which may match.
+type KNNQuery struct {
+	Field         string    `json:"field"`
+	QueryVector   []float32 `json:"query_vector"`
+	K             int       `json:"k"`
+	NumCandidates int       `json:"num_candidates"`
+	Filter        SubQuery  `json:"filter,omitempty"`
+}
+
+// Highlight configures the highlighting of matches in the given fields. The
+// PreTags and PostTags wrap each match, and default to <em> and </em>.
+type Highlight struct {
+	Fields   map[string]HighlightField `json:"fields"`
+	PreTags  []string                  `json:"pre_tags,omitempty"`
+	PostTags []string                  `json:"post_tags,omitempty"`
+}
+
+// HighlightField configures the highlighting of one field. Type is eg.
+// "unified", "plain", or "fvh". Zero values leave the defaults, except for
+// NumberOfFragments, which is a pointer, as 0 is meaningful: it highlights
+// the whole field as one fragment. See Int.
+type HighlightField struct {
+	FragmentSize      int    `json:"fragment_size,omitempty"`
+	NumberOfFragments *int   `json:"number_of_fragments,omitempty"`
+	Type              string `json:"type,omitempty"`
+}
+
+// SourceFilter is the object form of _source, which includes and excludes
+// fields by pattern.
+type SourceFilter struct {
+	Includes []string `json:"includes,omitempty"`
+	Excludes []string `json:"excludes,omitempty"`
+}
+
+// SortField is one field of a SearchRequest's Sort. Order is "asc" or "desc",
+// or empty for the field's default. Mode, eg. "min" or "avg", picks the value
+// to sort by for multi-valued fields, and Missing, eg. "_last", places
+// documents without the field. The special fields "_score" and "_doc" take
+// only an Order.
+type SortField struct {
+	Field   string
+	Order   string
+	Mode    string
+	Missing string
+}
+
+// SortBySeqNo sorts hits by ascending _seq_no, eg. for an incremental export
+// which resumes from the last SeqNo it saw. Sequence numbers are only ordered
+// within a shard. Set SearchParams.SeqNoPrimaryTerm to get each Hit's SeqNo.
+var SortBySeqNo = SortField{Field: "_seq_no", Order: "asc"}
+
+func (f SortField) MarshalJSON() ([]byte, error) {
+	if f.Field == "" {
+		return nil, fmt.Errorf("sort field is required")
+	}
+
+	if err := oneOf("order", f.Order, "", "asc", "desc"); err != nil {
+		return nil, err
+	}
+
+	if (f.Field == "_score" || f.Field == "_doc") && (f.Mode != "" || f.Missing != "") {
+		return nil, fmt.Errorf("sort by %s takes only an order", f.Field)
+	}
+
+	options := map[string]string{}
+	for key, value := range map[string]string{"order": f.Order, "mode": f.Mode, "missing": f.Missing} {
+		if value != "" {
+			options[key] = value
+		}
+	}
+
+	if len(options) == 0 {
+		return json.Marshal(f.Field)
+	}
+	return json.Marshal(map[string]interface{}{f.Field: options})
 }
 
 func (r SearchRequest) EncodeMultiHeader(enc *json.Encoder) error {
@@ -61,13 +381,137 @@ func (r SearchRequest) EncodeMultiHeader(enc *json.Encoder) error {
 }
 
 func (r SearchRequest) EncodeQuery(enc *json.Encoder) error {
-	return enc.Encode(r.Query)
+	body, err := r.body()
+	if err != nil {
+		return err
+	}
+
+	return enc.Encode(body)
+}
+
+// fields returns the body fields set on the request, besides the Query.
+func (r SearchRequest) fields() (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+
+	switch r.Source.(type) {
+	case nil:
+	case bool, string, []string, SourceFilter, *SourceFilter:
+		fields["_source"] = r.Source
+	default:
+		return nil, fmt.Errorf("invalid _source type %T", r.Source)
+	}
+
+	if len(r.Sort) > 0 {
+		fields["sort"] = r.Sort
+	}
+
+	if r.TrackScores {
+		fields["track_scores"] = true
+	}
+
+	if r.KNN != nil {
+		fields["knn"] = r.KNN
+	}
+
+	if len(r.Suggest) > 0 {
+		fields["suggest"] = r.Suggest
+	}
+
+	if r.Highlight != nil {
+		fields["highlight"] = r.Highlight
+	}
+
+	if r.Profile {
+		fields["profile"] = true
+	}
+
+	if r.From != 0 {
+		fields["from"] = r.From
+	}
+
+	if r.Size != nil {
+		fields["size"] = *r.Size
+	}
+
+	if r.PostFilter != nil {
+		fields["post_filter"] = r.PostFilter
+	}
+
+	if len(r.Aggs) > 0 {
+		fields["aggs"] = r.Aggs
+	}
+
+	if r.AggsOnly {
+		if r.Size != nil && *r.Size != 0 {
+			return nil, fmt.Errorf("size %d with aggregations only", *r.Size)
+		}
+		fields["size"] = 0
+	}
+
+	return fields, nil
+}
+
+// body returns the search body: the Query, with any other body fields set on
+// the request merged into it.
+func (r SearchRequest) body() (interface{}, error) {
+	fields, err := r.fields()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) == 0 {
+		return r.Query, nil
+	}
+
+	body := map[string]interface{}{}
+	if r.Query != nil {
+		buf, err := json.Marshal(r.Query)
+		if err != nil {
+			return nil, err
+		}
+
+		var query map[string]json.RawMessage
+		if err := json.Unmarshal(buf, &query); err != nil {
+			return nil, fmt.Errorf("query must be a JSON object to set other body fields: %s", err)
+		}
+
+		for key, value := range query {
+			body[key] = value
+		}
+	}
+
+	for key, value := range fields {
+		body[key] = value
+	}
+
+	return body, nil
+}
+
+// hasBody reports whether the search is sent with a body.
+func (r SearchRequest) hasBody() bool {
+	fields, err := r.fields()
+	return r.Query != nil || err != nil || len(fields) > 0
+}
+
+func (r SearchRequest) method() string {
+	switch {
+	case r.Method != "":
+		return r.Method
+	case r.hasBody():
+		return "POST"
+	default:
+		return "GET"
+	}
 }
 
 func (r SearchRequest) Request(uri *url.URL) (*http.Request, error) {
 	uri.Path = r.Path()
 	uri.RawQuery = r.Params.Values().Encode()
 
+	if !r.hasBody() {
+		return http.NewRequest(r.method(), uri.String(), nil)
+	}
+
 	buf := new(bytes.Buffer)
 	enc := json.NewEncoder(buf)
 
@@ -75,33 +519,44 @@ func (r SearchRequest) Request(uri *url.URL) (*http.Request, error) {
 		return nil, err
 	}
 
-	return http.NewRequest("GET", uri.String(), buf)
+	return http.NewRequest(r.method(), uri.String(), buf)
 }
 
 func (r SearchRequest) Path() string {
+	return typesPath(r.Params.Indices, r.Params.Types, "_search")
+}
+
+// Helper function which builds the path for an API that operates on a set of
+// indices and types, eg. "/i1/t1,t2/_search". No indices means all indices,
+// and no types means all types.
+func typesPath(indices, types []string, endpoint string) string {
 	switch true {
-	case len(r.Params.Indices) == 0 && len(r.Params.Types) == 0:
+	case len(indices) == 0 && len(types) == 0:
 		return fmt.Sprintf(
-			"/_search", // all indices, all types
+			"/%s", // all indices, all types
+			endpoint,
 		)
 
-	case len(r.Params.Indices) > 0 && len(r.Params.Types) == 0:
+	case len(indices) > 0 && len(types) == 0:
 		return fmt.Sprintf(
-			"/%s/_search",
-			strings.Join(r.Params.Indices, ","),
+			"/%s/%s",
+			strings.Join(indices, ","),
+			endpoint,
 		)
 
-	case len(r.Params.Indices) == 0 && len(r.Params.Types) > 0:
+	case len(indices) == 0 && len(types) > 0:
 		return fmt.Sprintf(
-			"/_all/%s/_search",
-			strings.Join(r.Params.Types, ","),
+			"/_all/%s/%s",
+			strings.Join(types, ","),
+			endpoint,
 		)
 
-	case len(r.Params.Indices) > 0 && len(r.Params.Types) > 0:
+	case len(indices) > 0 && len(types) > 0:
 		return fmt.Sprintf(
-			"/%s/%s/_search",
-			strings.Join(r.Params.Indices, ","),
-			strings.Join(r.Params.Types, ","),
+			"/%s/%s/%s",
+			strings.Join(indices, ","),
+			strings.Join(types, ","),
+			endpoint,
 		)
 	}
 	panic("unreachable")
@@ -111,17 +566,68 @@ func (r SearchRequest) Path() string {
 //
 //
 
+type CountParams struct {
+	Routing    string
+	Preference string
+
+	CommonParams
+}
+
+func (p CountParams) Values() url.Values {
+	return p.CommonParams.merge(values(map[string]string{
+		"routing":    p.Routing,
+		"preference": p.Preference,
+	}))
+}
+
+// CountRequest counts the documents matching the Query, or all documents if
+// it's nil, without fetching them. Its response is a CountResponse.
+type CountRequest struct {
+	Indices []string
+	Types   []string
+	Query   SubQuery // eg. QueryWrapper(...)
+	Params  CountParams
+}
+
+func (r CountRequest) Path() string {
+	return typesPath(r.Indices, r.Types, "_count")
+}
+
+func (r CountRequest) Request(uri *url.URL) (*http.Request, error) {
+	uri.Path = r.Path()
+	uri.RawQuery = r.Params.Values().Encode()
+
+	if r.Query == nil {
+		return http.NewRequest("GET", uri.String(), nil)
+	}
+
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+
+	if err := enc.Encode(r.Query); err != nil {
+		return nil, err
+	}
+
+	return http.NewRequest("POST", uri.String(), buf)
+}
+
+//
+//
+//
+
 type MultiSearchParams struct {
 	Indices []string
 	Types   []string
 
 	SearchType string
+
+	CommonParams
 }
 
 func (p MultiSearchParams) Values() url.Values {
-	return values(map[string]string{
+	return p.CommonParams.merge(values(map[string]string{
 		"search_type": p.SearchType,
-	})
+	}))
 }
 
 type MultiSearchRequest struct {
@@ -129,6 +635,32 @@ type MultiSearchRequest struct {
 	Requests []SearchRequest
 }
 
+// NewMultiSearch batches the passed SearchRequests into a MultiSearchRequest
+// with default params.
+func NewMultiSearch(requests ...SearchRequest) MultiSearchRequest {
+	return MultiSearchRequest{
+		Requests: requests,
+	}
+}
+
+// Validate checks that the header and body of each request can be encoded.
+// It returns an error identifying the first request which can't. Each one
+// that can is encoded as compact JSON, so takes exactly the two lines which
+// the multi-search format requires.
+func (r MultiSearchRequest) Validate() error {
+	enc := json.NewEncoder(ioutil.Discard)
+	for i, req := range r.Requests {
+		if err := req.EncodeMultiHeader(enc); err != nil {
+			return fmt.Errorf("request %d: %s", i, err)
+		}
+		if err := req.EncodeQuery(enc); err != nil {
+			return fmt.Errorf("request %d: %s", i, err)
+		}
+	}
+
+	return nil
+}
+
 func (r MultiSearchRequest) Request(uri *url.URL) (*http.Request, error) {
 	uri.Path = "/_msearch"
 	uri.RawQuery = r.Params.Values().Encode()
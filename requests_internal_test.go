@@ -0,0 +1,61 @@
+package elasticsearch
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestMergeValues(t *testing.T) {
+	for _, tuple := range []struct {
+		dst       url.Values
+		src       url.Values
+		overwrite bool
+		expected  url.Values
+	}{
+		{
+			dst:       url.Values{"routing": {"a"}},
+			src:       url.Values{"routing": {"b"}, "timeout": {"1m"}},
+			overwrite: false,
+			expected:  url.Values{"routing": {"a"}, "timeout": {"1m"}},
+		},
+		{
+			dst:       url.Values{"routing": {"a"}},
+			src:       url.Values{"routing": {"b"}, "timeout": {"1m"}},
+			overwrite: true,
+			expected:  url.Values{"routing": {"b"}, "timeout": {"1m"}},
+		},
+		{
+			dst:       url.Values{"filter_path": {"took"}},
+			src:       url.Values{"filter_path": {"hits.hits._id", "hits.total"}},
+			overwrite: false,
+			expected:  url.Values{"filter_path": {"took"}},
+		},
+		{
+			dst:       url.Values{"filter_path": {"took"}},
+			src:       url.Values{"filter_path": {"hits.hits._id", "hits.total"}},
+			overwrite: true,
+			expected:  url.Values{"filter_path": {"hits.hits._id", "hits.total"}},
+		},
+		{
+			dst:       nil,
+			src:       url.Values{"preference": {"_local", "_only_nodes:n1"}},
+			overwrite: false,
+			expected:  url.Values{"preference": {"_local", "_only_nodes:n1"}},
+		},
+	} {
+		if expected, got := tuple.expected, mergeValues(tuple.dst, tuple.src, tuple.overwrite); !reflect.DeepEqual(expected, got) {
+			t.Errorf("merge %v into %v (overwrite %v): expected %v; got %v", tuple.src, tuple.dst, tuple.overwrite, expected, got)
+		}
+	}
+}
+
+func TestMergeValuesCopies(t *testing.T) {
+	src := url.Values{"routing": {"a", "b"}}
+	dst := mergeValues(url.Values{}, src, false)
+
+	dst["routing"][0] = "c"
+	if expected, got := "a", src.Get("routing"); expected != got {
+		t.Errorf("expected src routing = %q after modifying dst; got %q", expected, got)
+	}
+}
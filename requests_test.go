@@ -15,71 +15,71 @@ func TestSearchRequestPath(t *testing.T) {
 	}{
 		{
 			r: es.SearchRequest{
-				es.SearchParams{
+				Params: es.SearchParams{
 					Indices: []string{},
 					Types:   []string{},
 				},
-				nil,
+				Query: nil,
 			},
 			expected: "/_search",
 		},
 		{
 			r: es.SearchRequest{
-				es.SearchParams{
+				Params: es.SearchParams{
 					Indices: []string{"i1"},
 					Types:   []string{},
 				},
-				nil,
+				Query: nil,
 			},
 			expected: "/i1/_search",
 		},
 		{
 			r: es.SearchRequest{
-				es.SearchParams{
+				Params: es.SearchParams{
 					Indices: []string{},
 					Types:   []string{"t1"},
 				},
-				nil,
+				Query: nil,
 			},
 			expected: "/_all/t1/_search",
 		},
 		{
 			r: es.SearchRequest{
-				es.SearchParams{
+				Params: es.SearchParams{
 					Indices: []string{"i1"},
 					Types:   []string{"t1"},
 				},
-				nil,
+				Query: nil,
 			},
 			expected: "/i1/t1/_search",
 		},
 		{
 			r: es.SearchRequest{
-				es.SearchParams{
+				Params: es.SearchParams{
 					Indices: []string{"i1", "i2"},
 					Types:   []string{},
 				},
-				nil,
+				Query: nil,
 			},
 			expected: "/i1,i2/_search",
 		},
 		{
 			r: es.SearchRequest{
-				es.SearchParams{
+				Params: es.SearchParams{
 					Indices: []string{},
 					Types:   []string{"t1", "t2", "t3"},
 				},
-				nil,
+				Query: nil,
 			},
 			expected: "/_all/t1,t2,t3/_search",
 		},
 		{
 			r: es.SearchRequest{
-				es.SearchParams{
+				Params: es.SearchParams{
 					Indices: []string{"i1", "i2"},
 					Types:   []string{"t1", "t2", "t3"},
 				},
-				nil,
+				Query: nil,
 			},
 			expected: "/i1,i2/t1,t2,t3/_search",
 		},
@@ -90,6 +90,39 @@ func TestSearchRequestPath(t *testing.T) {
 	}
 }
 
+func TestSearchRequestMethod(t *testing.T) {
+	for _, tuple := range []struct {
+		r        es.SearchRequest
+		expected string
+	}{
+		{
+			r:        es.SearchRequest{},
+			expected: "GET",
+		},
+		{
+			r:        es.SearchRequest{Query: es.MatchAllQuery()},
+			expected: "POST",
+		},
+		{
+			r:        es.SearchRequest{Query: es.MatchAllQuery(), Method: "GET"},
+			expected: "GET",
+		},
+	} {
+		request, err := tuple.r.Request(&url.URL{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, got := tuple.expected, request.Method; expected != got {
+			t.Errorf("%v: expected method = %q; got %q", tuple.r, expected, got)
+		}
+
+		if tuple.r.Query == nil && request.Body != nil {
+			t.Errorf("%v: expected no body", tuple.r)
+		}
+	}
+}
+
 func TestSearchRequestValues(t *testing.T) {
 	for _, tuple := range []struct {
 		r        es.SearchRequest
@@ -103,6 +136,93 @@ func TestSearchRequestValues(t *testing.T) {
 			},
 			expected: "preference=foo",
 		},
+		{
+			r: es.SearchRequest{
+				Params: es.SearchParams{
+					Version:          "true",
+					SeqNoPrimaryTerm: "true",
+				},
+			},
+			expected: "seq_no_primary_term=true&version=true",
+		},
+		{
+			r: es.SearchRequest{
+				Params: es.SearchParams{
+					AllowPartialSearchResults: "false",
+				},
+			},
+			expected: "allow_partial_search_results=false",
+		},
+		{
+			r: es.SearchRequest{
+				Params: es.SearchParams{
+					AllowPartialSearchResults: "true",
+				},
+			},
+			expected: "allow_partial_search_results=true",
+		},
+		{
+			r: es.SearchRequest{
+				Params: es.SearchParams{
+					BatchedReduceSize: "256",
+				},
+			},
+			expected: "batched_reduce_size=256",
+		},
+		{
+			r: es.SearchRequest{
+				Params: es.SearchParams{
+					RestTotalHitsAsInt: "true",
+				},
+			},
+			expected: "rest_total_hits_as_int=true",
+		},
+		{
+			r: es.SearchRequest{
+				Params: es.SearchParams{
+					MaxConcurrentShardRequests: "3",
+				},
+			},
+			expected: "max_concurrent_shard_requests=3",
+		},
+		{
+			r: es.SearchRequest{
+				Params: es.SearchParams{
+					TypedKeys: "true",
+				},
+			},
+			expected: "typed_keys=true",
+		},
+		{
+			r: es.SearchRequest{
+				Params: es.SearchParams{
+					CommonParams: es.CommonParams{
+						Human:      "true",
+						ErrorTrace: "true",
+					},
+				},
+			},
+			expected: "error_trace=true&human=true",
+		},
+		{
+			r: es.SearchRequest{
+				Params: es.SearchParams{
+					Timeout:      "1s",
+					CommonParams: es.CommonParams{Timeout: "1m"},
+				},
+			},
+			expected: "timeout=1s",
+		},
+		{
+			r: es.SearchRequest{
+				Params: es.SearchParams{
+					Preference:   "_local",
+					Routing:      "kimchy",
+					CommonParams: es.CommonParams{Pretty: "true"},
+				},
+			},
+			expected: "preference=_local&pretty=true&routing=kimchy",
+		},
 	} {
 		if expected, got := tuple.expected, tuple.r.Params.Values().Encode(); expected != got {
 			t.Errorf("%v: expected '%s', got '%s'", tuple.r, expected, got)
@@ -115,39 +235,39 @@ func TestMultiSearchRequestBody(t *testing.T) {
 		es.MultiSearchParams{},
 		[]es.SearchRequest{
 			es.SearchRequest{
-				es.SearchParams{
+				Params: es.SearchParams{
 					Indices: []string{},
 					Types:   []string{},
 				},
-				map[string]interface{}{"query": "1"},
+				Query: map[string]interface{}{"query": "1"},
 			},
 			es.SearchRequest{
-				es.SearchParams{
+				Params: es.SearchParams{
 					Indices: []string{"i1"},
 					Types:   []string{},
 				},
-				map[string]interface{}{"query": "2"},
+				Query: map[string]interface{}{"query": "2"},
 			},
 			es.SearchRequest{
-				es.SearchParams{
+				Params: es.SearchParams{
 					Indices: []string{},
 					Types:   []string{"t1"},
 				},
-				map[string]interface{}{"query": "3"},
+				Query: map[string]interface{}{"query": "3"},
 			},
 			es.SearchRequest{
-				es.SearchParams{
+				Params: es.SearchParams{
 					Indices: []string{"i1"},
 					Types:   []string{"t1"},
 				},
-				map[string]interface{}{"query": "4"},
+				Query: map[string]interface{}{"query": "4"},
 			},
 			es.SearchRequest{
-				es.SearchParams{
+				Params: es.SearchParams{
 					Indices: []string{"i1", "i2"},
 					Types:   []string{"t1", "t2", "t3"},
 				},
-				map[string]interface{}{"query": "5"},
+				Query: map[string]interface{}{"query": "5"},
 			},
 		},
 	}
@@ -181,3 +301,453 @@ func TestMultiSearchRequestBody(t *testing.T) {
 		t.Errorf("Body: expected:\n---\n%s\n---\ngot:\n---\n%s\n---\n", expected, got)
 	}
 }
+
+func TestNewMultiSearch(t *testing.T) {
+	m := es.NewMultiSearch(
+		es.SearchRequest{
+			Params: es.SearchParams{Indices: []string{"i1"}},
+			Query:  map[string]interface{}{"query": "1"},
+		},
+		es.SearchRequest{
+			Params: es.SearchParams{Types: []string{"t1"}},
+			Query:  map[string]interface{}{"query": "2"},
+		},
+	)
+
+	req, err := m.Request(&url.URL{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Join(
+		[]string{
+			`{"index":["i1"]}`,
+			`{"query":"1"}`,
+			`{"type":["t1"]}`,
+			`{"query":"2"}`,
+		},
+		"\n",
+	) + "\n"
+	got, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected != string(got) {
+		t.Errorf("Body: expected:\n---\n%s\n---\ngot:\n---\n%s\n---\n", expected, got)
+	}
+}
+
+func TestMultiSearchRequestValidate(t *testing.T) {
+	m := es.NewMultiSearch(
+		es.SearchRequest{
+			Params: es.SearchParams{Indices: []string{"i1"}},
+			Query:  map[string]interface{}{"query": "1"},
+		},
+	)
+
+	if err := m.Validate(); err != nil {
+		t.Errorf("expected no error; got %s", err)
+	}
+
+	m.Requests = append(m.Requests, es.SearchRequest{
+		Query: map[string]interface{}{"query": make(chan int)}, // can't be encoded
+	})
+
+	err := m.Validate()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if !strings.HasPrefix(err.Error(), "request 1:") {
+		t.Errorf("expected error to identify request 1; got %s", err)
+	}
+}
+
+// requestBytes fires f at an empty URL, returning the method, URL, and body
+// of the resulting request.
+func requestBytes(t *testing.T, f es.Fireable) string {
+	request, err := f.Request(&url.URL{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := request.Method + " " + request.URL.String() + "\n"
+	if request.Body == nil {
+		return s
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s + string(body)
+}
+
+func TestRequestsAreIdempotent(t *testing.T) {
+	params := es.IndexParams{Index: "twitter", Type: "tweet", Id: "1"}
+	doc := map[string]string{"user": "kimchy"}
+
+	for _, f := range []es.Fireable{
+		es.SearchRequest{
+			Params: es.SearchParams{Indices: []string{"twitter"}},
+			Query:  es.QueryWrapper(es.MatchAllQuery()),
+		},
+		es.NewMultiSearch(es.SearchRequest{Query: es.QueryWrapper(es.MatchAllQuery())}),
+		es.IndexRequest{params, doc},
+		es.CreateRequest{params, doc},
+		es.UpdateRequest{Params: params, Source: map[string]interface{}{"doc": doc}},
+		es.DeleteRequest{params},
+		es.NewBulk(es.IndexRequest{params, doc}, es.DeleteRequest{params}),
+		es.ClearCacheRequest{Indices: []string{"twitter"}},
+		es.IndexStatsRequest{Indices: []string{"twitter"}},
+		es.AddField("twitter", "tweet", "user", map[string]interface{}{"type": "string"}),
+		es.ReindexRequest{
+			Source: es.ReindexSource{Indices: []string{"twitter"}},
+			Dest:   es.ReindexDest{Index: "new_twitter"},
+		},
+		es.RethrottleRequest{TaskId: "1", RequestsPerSecond: "10"},
+	} {
+		if first, second := requestBytes(t, f), requestBytes(t, f); first != second {
+			t.Errorf("%T: not idempotent:\n---\n%s\n---\n%s\n---\n", f, first, second)
+		}
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	structured := es.SearchRequest{
+		Query: es.QueryWrapper(es.BoolQuery(es.BoolQueryParams{
+			Must:  es.TermQuery(es.TermQueryParams{Query: &es.Wrapper{Name: "user", Wrapped: "kimchy"}}),
+			Boost: 2,
+		})),
+	}
+
+	literal := es.SearchRequest{
+		Query: map[string]interface{}{
+			"query": map[string]interface{}{
+				"bool": map[string]interface{}{
+					"boost": 2,
+					"must":  map[string]interface{}{"term": map[string]interface{}{"user": "kimchy"}},
+				},
+			},
+		},
+	}
+
+	a, err := es.CanonicalJSON(structured)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := es.CanonicalJSON(literal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(a) != string(b) {
+		t.Errorf("expected identical canonical forms; got\n%s\n%s", a, b)
+	}
+
+	if expected, got := `{"query":{"bool":{"boost":2,"must":{"term":{"user":"kimchy"}}}}}`+"\n", string(a); expected != got {
+		t.Errorf("expected %s; got %s", expected, got)
+	}
+}
+
+func TestSearchRequestSource(t *testing.T) {
+	query := es.QueryWrapper(es.MatchAllQuery())
+
+	for _, tuple := range []struct {
+		source   interface{}
+		expected string
+	}{
+		{
+			source:   false,
+			expected: `{"_source":false,"query":{"match_all":{}}}`,
+		},
+		{
+			source:   []string{"user", "message"},
+			expected: `{"_source":["user","message"],"query":{"match_all":{}}}`,
+		},
+		{
+			source:   es.SourceFilter{Includes: []string{"obj.*"}, Excludes: []string{"*.secret"}},
+			expected: `{"_source":{"includes":["obj.*"],"excludes":["*.secret"]},"query":{"match_all":{}}}`,
+		},
+		{
+			source:   es.SourceFilter{Includes: []string{"user", "obj.*"}},
+			expected: `{"_source":{"includes":["user","obj.*"]},"query":{"match_all":{}}}`,
+		},
+		{
+			source:   &es.SourceFilter{Excludes: []string{"*.secret"}},
+			expected: `{"_source":{"excludes":["*.secret"]},"query":{"match_all":{}}}`,
+		},
+		{
+			source:   nil,
+			expected: `{"query":{"match_all":{}}}`,
+		},
+	} {
+		r := es.SearchRequest{Query: query, Source: tuple.source}
+
+		if expected, got := "POST /_search\n"+tuple.expected+"\n", requestBytes(t, r); expected != got {
+			t.Errorf("%v: expected %q; got %q", tuple.source, expected, got)
+		}
+	}
+}
+
+func TestSearchRequestInvalidSource(t *testing.T) {
+	r := es.SearchRequest{Source: 42}
+	if _, err := r.Request(&url.URL{}); err == nil {
+		t.Errorf("expected an error for an int _source")
+	}
+}
+
+func TestSearchRequestSortTrackScores(t *testing.T) {
+	r := es.SearchRequest{
+		Query:       es.QueryWrapper(es.MatchAllQuery()),
+		Sort:        []es.SubQuery{map[string]string{"date": "desc"}, "_score"},
+		TrackScores: true,
+	}
+
+	expected := "POST /_search\n" + `{"query":{"match_all":{}},"sort":[{"date":"desc"},"_score"],"track_scores":true}` + "\n"
+	if got := requestBytes(t, r); expected != got {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+}
+
+func TestSearchRequestSortFields(t *testing.T) {
+	r := es.SearchRequest{
+		Query: es.QueryWrapper(es.MatchAllQuery()),
+		Sort: []es.SubQuery{
+			es.SortField{Field: "price", Order: "asc", Mode: "avg", Missing: "_last"},
+			es.SortField{Field: "_score", Order: "desc"},
+			es.SortField{Field: "_doc"},
+		},
+	}
+
+	expected := "POST /_search\n" + `{"query":{"match_all":{}},"sort":[{"price":{"missing":"_last","mode":"avg","order":"asc"}},{"_score":{"order":"desc"}},"_doc"]}` + "\n"
+	if got := requestBytes(t, r); expected != got {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+
+	for _, field := range []es.SortField{
+		{Field: "price", Order: "up"},
+		{Field: "_score", Mode: "avg"},
+		{Order: "asc"},
+	} {
+		r := es.SearchRequest{Sort: []es.SubQuery{field}}
+		if _, err := r.Request(&url.URL{}); err == nil {
+			t.Errorf("%+v: expected an error", field)
+		}
+	}
+}
+
+func TestSearchRequestSortBySeqNo(t *testing.T) {
+	r := es.SearchRequest{
+		Params: es.SearchParams{Indices: []string{"twitter"}, SeqNoPrimaryTerm: "true"},
+		Query:  es.QueryWrapper(es.Range("_seq_no", map[string]interface{}{"gt": 41})),
+		Sort:   []es.SubQuery{es.SortBySeqNo},
+	}
+
+	expected := "POST /twitter/_search?seq_no_primary_term=true\n" + `{"query":{"range":{"_seq_no":{"gt":41}}},"sort":[{"_seq_no":{"order":"asc"}}]}` + "\n"
+	if got := requestBytes(t, r); expected != got {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+}
+
+func TestSearchRequestHighlight(t *testing.T) {
+	r := es.SearchRequest{
+		Query: es.QueryWrapper(es.Match("message", "search")),
+		Highlight: &es.Highlight{
+			Fields: map[string]es.HighlightField{
+				"message": {FragmentSize: 150, NumberOfFragments: es.Int(3), Type: "unified"},
+				"summary": {NumberOfFragments: es.Int(0)},
+				"title":   {},
+			},
+			PreTags:  []string{"<b>"},
+			PostTags: []string{"</b>"},
+		},
+	}
+
+	expected := "POST /_search\n" + `{"highlight":{"fields":{"message":{"fragment_size":150,"number_of_fragments":3,"type":"unified"},"summary":{"number_of_fragments":0},"title":{}},"pre_tags":["\u003cb\u003e"],"post_tags":["\u003c/b\u003e"]},"query":{"match":{"message":"search"}}}` + "\n"
+	if got := requestBytes(t, r); expected != got {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+}
+
+func TestSearchRequestKNN(t *testing.T) {
+	r := es.SearchRequest{
+		KNN: &es.KNNQuery{
+			Field:         "title_vector",
+			QueryVector:   []float32{0.1, -2, 3.5},
+			K:             10,
+			NumCandidates: 100,
+		},
+	}
+
+	expected := "POST /_search\n" + `{"knn":{"field":"title_vector","query_vector":[0.1,-2,3.5],"k":10,"num_candidates":100}}` + "\n"
+	if got := requestBytes(t, r); expected != got {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+}
+
+func TestSearchRequestCompletionSuggest(t *testing.T) {
+	r := es.SearchRequest{}
+	r.CompletionSuggest("song-suggest", "suggest", "nir")
+
+	expected := "POST /_search\n" + `{"suggest":{"song-suggest":{"completion":{"field":"suggest"},"prefix":"nir"}}}` + "\n"
+	if got := requestBytes(t, r); expected != got {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+}
+
+func TestSearchRequestAggsOnly(t *testing.T) {
+	r := es.SearchRequest{
+		Query: map[string]interface{}{
+			"query": es.TermQuery(es.TermQueryParams{Query: &es.Wrapper{Name: "user", Wrapped: "kimchy"}}),
+			"aggs": map[string]interface{}{
+				"tags": map[string]interface{}{"terms": map[string]string{"field": "tags"}},
+			},
+		},
+		AggsOnly: true,
+	}
+
+	expected := "POST /_search\n" + `{"aggs":{"tags":{"terms":{"field":"tags"}}},"query":{"term":{"user":"kimchy"}},"size":0}` + "\n"
+	if got := requestBytes(t, r); expected != got {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+}
+
+func TestSearchRequestProfile(t *testing.T) {
+	r := es.SearchRequest{Query: es.QueryWrapper(es.MatchAllQuery()), Profile: true}
+
+	expected := "POST /_search\n" + `{"profile":true,"query":{"match_all":{}}}` + "\n"
+	if got := requestBytes(t, r); expected != got {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+}
+
+func TestSearchRequestAggs(t *testing.T) {
+	r := es.SearchRequest{
+		Query:    es.QueryWrapper(es.Term("user", "kimchy")),
+		Aggs:     map[string]es.SubQuery{"tags": es.TermsAgg("tags", 5)},
+		AggsOnly: true,
+	}
+
+	expected := "POST /_search\n" + `{"aggs":{"tags":{"terms":{"field":"tags","size":5}}},"query":{"term":{"user":"kimchy"}},"size":0}` + "\n"
+	if got := requestBytes(t, r); expected != got {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+}
+
+func TestSearchRequestBodyFields(t *testing.T) {
+	r := es.SearchRequest{
+		Query:      es.QueryWrapper(es.Match("message", "search")),
+		PostFilter: es.Term("tag", "go"),
+		Aggs:       map[string]es.SubQuery{"tags": es.TermsAgg("tag", 10)},
+		Sort:       []es.SubQuery{es.SortField{Field: "post_date", Order: "desc"}, es.SortField{Field: "_score"}},
+		From:       10,
+		Size:       es.Size(10),
+	}
+
+	expected := "POST /_search\n" + `{` +
+		`"aggs":{"tags":{"terms":{"field":"tag","size":10}}},` +
+		`"from":10,` +
+		`"post_filter":{"term":{"tag":"go"}},` +
+		`"query":{"match":{"message":"search"}},` +
+		`"size":10,` +
+		`"sort":[{"post_date":{"order":"desc"}},"_score"]` +
+		`}` + "\n"
+
+	if got := requestBytes(t, r); expected != got {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+}
+
+func TestSearchRequestFromSize(t *testing.T) {
+	query := es.QueryWrapper(es.MatchAllQuery())
+
+	for _, tuple := range []struct {
+		r        es.SearchRequest
+		expected string
+	}{
+		{
+			r:        es.SearchRequest{Query: query, From: 20, Size: es.Size(10)},
+			expected: `{"from":20,"query":{"match_all":{}},"size":10}`,
+		},
+		{
+			r:        es.SearchRequest{Query: query, Size: es.Size(0)},
+			expected: `{"query":{"match_all":{}},"size":0}`,
+		},
+		{
+			r:        es.SearchRequest{Query: query, Size: es.Size(0), AggsOnly: true},
+			expected: `{"query":{"match_all":{}},"size":0}`,
+		},
+		{
+			r:        es.SearchRequest{Query: query},
+			expected: `{"query":{"match_all":{}}}`,
+		},
+	} {
+		if expected, got := "POST /_search\n"+tuple.expected+"\n", requestBytes(t, tuple.r); expected != got {
+			t.Errorf("expected %q; got %q", expected, got)
+		}
+	}
+
+	if _, err := (es.SearchRequest{Size: es.Size(10), AggsOnly: true}).Request(&url.URL{}); err == nil {
+		t.Errorf("expected an error for a non-zero size with aggregations only")
+	}
+
+	// Within a multi-search, they're part of each search's body.
+	got := requestBytes(t, es.NewMultiSearch(es.SearchRequest{From: 10, Size: es.Size(5)}))
+	if expected := "GET /_msearch\n{}\n{\"from\":10,\"size\":5}\n"; expected != got {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+}
+
+func TestCountRequestPath(t *testing.T) {
+	for _, tuple := range []struct {
+		r        es.CountRequest
+		expected string
+	}{
+		{
+			r:        es.CountRequest{Indices: []string{}, Types: []string{}},
+			expected: "/_count",
+		},
+		{
+			r:        es.CountRequest{Indices: []string{"i1"}, Types: []string{}},
+			expected: "/i1/_count",
+		},
+		{
+			r:        es.CountRequest{Indices: []string{}, Types: []string{"t1"}},
+			expected: "/_all/t1/_count",
+		},
+		{
+			r:        es.CountRequest{Indices: []string{"i1"}, Types: []string{"t1"}},
+			expected: "/i1/t1/_count",
+		},
+		{
+			r:        es.CountRequest{Indices: []string{"i1", "i2"}, Types: []string{"t1", "t2", "t3"}},
+			expected: "/i1,i2/t1,t2,t3/_count",
+		},
+	} {
+		if expected, got := tuple.expected, tuple.r.Path(); expected != got {
+			t.Errorf("%v: expected '%s', got '%s'", tuple.r, expected, got)
+		}
+	}
+}
+
+func TestCountRequest(t *testing.T) {
+	r := es.CountRequest{
+		Indices: []string{"twitter"},
+		Query: es.QueryWrapper(es.TermQuery(es.TermQueryParams{
+			Query: &es.Wrapper{Name: "user", Wrapped: "kimchy"},
+		})),
+		Params: es.CountParams{Routing: "kimchy"},
+	}
+
+	expected := "POST /twitter/_count?routing=kimchy\n" + `{"query":{"term":{"user":"kimchy"}}}` + "\n"
+	if got := requestBytes(t, r); expected != got {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+
+	if expected, got := "GET /_count\n", requestBytes(t, es.CountRequest{}); expected != got {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+}
@@ -1,25 +1,290 @@
 package elasticsearch
 
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"time"
+)
+
 // SearchResponse represents the response given by ElasticSearch from a search
 // query.
 type SearchResponse struct {
-	Took int `json:"took"` // ms
+	Took     int    `json:"took"`                 // ms
+	ScrollID string `json:"_scroll_id,omitempty"` // when scrolling
+	Shards   Shards `json:"_shards"`
+
+	HitsWrapper SearchHits `json:"hits"`
 
-	HitsWrapper struct {
-		Total int `json:"total"`
-		Hits  []struct {
-			Index string   `json:"_index"`
-			Type  string   `json:"_type"`
-			ID    string   `json:"_id"`
-			Score *float64 `json:"_score"` // can be 'null' with constant_score
-		} `json:"hits,omitempty"`
-	} `json:"hits"`
+	Facets       map[string]FacetResponse   `json:"facets,omitempty"`
+	Aggregations map[string]json.RawMessage `json:"aggregations,omitempty"`
+	Suggest      map[string]json.RawMessage `json:"suggest,omitempty"`
 
-	Facets map[string]FacetResponse `json:"facets,omitempty"`
+	// Profile is only returned for searches made with Profile set.
+	Profile *SearchProfile `json:"profile,omitempty"`
 
 	TimedOut bool   `json:"timed_out,omitempty"`
 	Error    string `json:"error,omitempty"`
 	Status   int    `json:"status,omitempty"`
+
+	// Extra captures any top-level fields not decoded above, eg. those added
+	// by newer versions of ElasticSearch. It's meant for debugging, and only
+	// set by ParseSearchResponseWithExtra.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// unknownFields returns the fields of the JSON object in data which don't
+// correspond to any field of the struct pointed to by v.
+func unknownFields(data []byte, v interface{}) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	t := reflect.TypeOf(v).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Name
+		if tag := t.Field(i).Tag.Get("json"); tag != "" {
+			name = strings.Split(tag, ",")[0]
+		}
+		for key := range fields {
+			if strings.EqualFold(key, name) {
+				delete(fields, key)
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+// SearchHits are the hits of a search, and how many documents matched it.
+type SearchHits struct {
+	// Total is the number of matching documents. Newer versions of
+	// ElasticSearch may count only up to a limit, in which case Relation is
+	// "gte" rather than "eq".
+	Total    int    `json:"total"`
+	Relation string `json:"-"`
+
+	MaxScore *float64 `json:"max_score"` // can be null, eg. when sorting
+	Hits     []Hit    `json:"hits,omitempty"`
+}
+
+// UnmarshalJSON decodes the total as either a plain number, from older
+// versions of ElasticSearch, or a {"value": n, "relation": "eq"} object.
+func (h *SearchHits) UnmarshalJSON(data []byte) error {
+	type plain SearchHits // without this method, to avoid recursion
+	var v struct {
+		*plain
+		Total json.RawMessage `json:"total"`
+	}
+	v.plain = (*plain)(h)
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	h.Total, h.Relation = 0, ""
+	if len(v.Total) == 0 || string(v.Total) == "null" {
+		return nil
+	}
+
+	if err := json.Unmarshal(v.Total, &h.Total); err == nil {
+		h.Relation = "eq"
+		return nil
+	}
+
+	var total struct {
+		Value    int    `json:"value"`
+		Relation string `json:"relation"`
+	}
+	if err := json.Unmarshal(v.Total, &total); err != nil {
+		return fmt.Errorf("invalid hits total %s", v.Total)
+	}
+	h.Total, h.Relation = total.Value, total.Relation
+	return nil
+}
+
+// Hit is a single document matched by a search.
+type Hit struct {
+	Index string   `json:"_index"`
+	Type  string   `json:"_type"`
+	ID    string   `json:"_id"`
+	Score *float64 `json:"_score"` // can be 'null' with constant_score
+
+	Source json.RawMessage `json:"_source,omitempty"`
+
+	// Highlight holds the highlighted fragments of each field, for searches
+	// made with a Highlight.
+	Highlight map[string][]string `json:"highlight,omitempty"`
+
+	// Version, SeqNo, and PrimaryTerm are only returned when requested via
+	// SearchParams.
+	Version     int64 `json:"_version,omitempty"`
+	SeqNo       int64 `json:"_seq_no,omitempty"`
+	PrimaryTerm int64 `json:"_primary_term,omitempty"`
+
+	// Node and Shard identify where the hit was served from. ElasticSearch
+	// only includes them for some requests, eg. with explain=true. The shard
+	// is given as index and shard number, eg. "[twitter][0]".
+	Node  string `json:"_node,omitempty"`
+	Shard string `json:"_shard,omitempty"`
+}
+
+// NumHits returns the number of hits in the response, which may be fewer than
+// the total number of matching documents, HitsWrapper.Total.
+func (r *SearchResponse) NumHits() int {
+	return len(r.HitsWrapper.Hits)
+}
+
+// IsEmpty reports whether the response has no hits.
+func (r *SearchResponse) IsEmpty() bool {
+	return r.NumHits() == 0
+}
+
+// TookDuration returns how long ElasticSearch took to execute the search.
+func (r *SearchResponse) TookDuration() time.Duration {
+	return time.Duration(r.Took) * time.Millisecond
+}
+
+// Aggregation returns the named aggregation from the response. If the search
+// was made with typed_keys, the type prefix is stripped from the name and
+// returned as typ.
+func (r *SearchResponse) Aggregation(name string) (raw json.RawMessage, typ string, ok bool) {
+	if raw, ok := r.Aggregations[name]; ok {
+		return raw, "", true
+	}
+
+	for key, raw := range r.Aggregations {
+		if i := strings.Index(key, "#"); i >= 0 && key[i+1:] == name {
+			return raw, key[:i], true
+		}
+	}
+
+	return nil, "", false
+}
+
+// Bucket is one bucket of a bucket aggregation, eg. a TermsAgg. Key is a
+// string or a number; date keys are milliseconds since the epoch, and also
+// formatted in KeyAsString.
+type Bucket struct {
+	Key         interface{} `json:"key"`
+	KeyAsString string      `json:"key_as_string,omitempty"`
+	DocCount    int64       `json:"doc_count"`
+}
+
+// Buckets returns the buckets of the named aggregation, found as by
+// Aggregation.
+func (r *SearchResponse) Buckets(name string) ([]Bucket, error) {
+	raw, _, ok := r.Aggregation(name)
+	if !ok {
+		return nil, fmt.Errorf("no aggregation named %q", name)
+	}
+
+	var agg struct {
+		Buckets []Bucket `json:"buckets"`
+	}
+	if err := json.Unmarshal(raw, &agg); err != nil {
+		return nil, err
+	}
+	return agg.Buckets, nil
+}
+
+// CompletionOptions returns the text of each option suggested by the named
+// completion suggester, eg. from SearchRequest.CompletionSuggest.
+func (r *SearchResponse) CompletionOptions(name string) ([]string, error) {
+	raw, ok := r.Suggest[name]
+	if !ok {
+		return nil, fmt.Errorf("no suggestions named %q", name)
+	}
+
+	var entries []struct {
+		Options []struct {
+			Text string `json:"text"`
+		} `json:"options"`
+	}
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, err
+	}
+
+	a := []string{}
+	for _, entry := range entries {
+		for _, option := range entry.Options {
+			a = append(a, option.Text)
+		}
+	}
+	return a, nil
+}
+
+// DecodeHits unmarshals the source of each hit in the response into a T.
+func DecodeHits[T any](r *SearchResponse) ([]T, error) {
+	a := make([]T, 0, len(r.HitsWrapper.Hits))
+	for _, hit := range r.HitsWrapper.Hits {
+		var t T
+		if err := json.Unmarshal(hit.Source, &t); err != nil {
+			return nil, fmt.Errorf("hit %s: %s", hit.ID, err)
+		}
+		a = append(a, t)
+	}
+	return a, nil
+}
+
+// SearchProfile breaks down where the time of a search was spent, on each
+// shard.
+type SearchProfile struct {
+	Shards []ShardProfile `json:"shards"`
+}
+
+type ShardProfile struct {
+	ID           string           `json:"id"` // eg. "[nodeID][twitter][0]"
+	Searches     []QueriesProfile `json:"searches"`
+	Aggregations []QueryProfile   `json:"aggregations,omitempty"`
+}
+
+// QueriesProfile times the queries executed in one search of a shard, and
+// the collectors which gathered their hits.
+type QueriesProfile struct {
+	Query       []QueryProfile     `json:"query"`
+	RewriteTime int64              `json:"rewrite_time"` // ns
+	Collector   []CollectorProfile `json:"collector"`
+}
+
+// QueryProfile times a query, or an aggregation, and its children. Type is
+// the Lucene class it was executed as, eg. "BooleanQuery" or
+// "LongTermsAggregator", and Breakdown splits TimeInNanos into low-level
+// steps, eg. "score" or "build_aggregation".
+type QueryProfile struct {
+	Type        string           `json:"type"`
+	Description string           `json:"description"`
+	TimeInNanos int64            `json:"time_in_nanos"`
+	Breakdown   map[string]int64 `json:"breakdown"`
+	Children    []QueryProfile   `json:"children,omitempty"`
+}
+
+// Time returns TimeInNanos as a Duration.
+func (p QueryProfile) Time() time.Duration {
+	return time.Duration(p.TimeInNanos)
+}
+
+type CollectorProfile struct {
+	Name        string             `json:"name"`
+	Reason      string             `json:"reason"`
+	TimeInNanos int64              `json:"time_in_nanos"`
+	Children    []CollectorProfile `json:"children,omitempty"`
+}
+
+// EachWithMeta calls fn with each hit in the response, and its raw source,
+// eg. to correlate each hit's ID with the document decoded from its source.
+// It stops at the first error returned by fn.
+func (r *SearchResponse) EachWithMeta(fn func(Hit, json.RawMessage) error) error {
+	for _, hit := range r.HitsWrapper.Hits {
+		if err := fn(hit, hit.Source); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 type FacetResponse struct {
@@ -36,3 +301,80 @@ type FacetResponse struct {
 type MultiSearchResponse struct {
 	Responses []SearchResponse `json:"responses"`
 }
+
+// Shards summarizes how many shards took part in an operation.
+type Shards struct {
+	Total      int `json:"total"`
+	Successful int `json:"successful"`
+	Failed     int `json:"failed"`
+}
+
+// CountResponse represents the response given by ElasticSearch from a count
+// query.
+type CountResponse struct {
+	Count  int64  `json:"count"`
+	Shards Shards `json:"_shards"`
+
+	Error  string `json:"error,omitempty"`
+	Status int    `json:"status,omitempty"`
+}
+
+// ParseSearchResponse decodes a SearchResponse from r.
+func ParseSearchResponse(r io.Reader) (*SearchResponse, error) {
+	var response SearchResponse
+	if err := json.NewDecoder(r).Decode(&response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// ParseSearchResponseWithExtra decodes a SearchResponse from r, like
+// ParseSearchResponse, and also captures any unknown top-level fields in its
+// Extra. It's slower, so it's meant for debugging.
+func ParseSearchResponseWithExtra(r io.Reader) (*SearchResponse, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var response SearchResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+
+	extra, err := unknownFields(data, &response)
+	if err != nil {
+		return nil, err
+	}
+	if len(extra) > 0 {
+		response.Extra = extra
+	}
+	return &response, nil
+}
+
+// ParseCountResponse decodes a CountResponse from r.
+func ParseCountResponse(r io.Reader) (*CountResponse, error) {
+	var response CountResponse
+	if err := json.NewDecoder(r).Decode(&response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// ValidateQueryResponse represents the response given by ElasticSearch from a
+// _validate/query request. Explanations are only returned with explain=true.
+type ValidateQueryResponse struct {
+	Valid        bool               `json:"valid"`
+	Shards       Shards             `json:"_shards"`
+	Explanations []QueryExplanation `json:"explanations,omitempty"`
+
+	Error  string `json:"error,omitempty"`
+	Status int    `json:"status,omitempty"`
+}
+
+type QueryExplanation struct {
+	Index       string `json:"index"`
+	Valid       bool   `json:"valid"`
+	Explanation string `json:"explanation,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
@@ -0,0 +1,737 @@
+package elasticsearch_test
+
+import (
+	"encoding/json"
+	"fmt"
+	es "github.com/peterbourgon/elasticsearch"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchResponseHitShardAndNode(t *testing.T) {
+	body := `{
+		"took": 3,
+		"hits": {
+			"total": 2,
+			"hits": [
+				{"_index": "twitter", "_type": "tweet", "_id": "1", "_score": 1.0, "_shard": "[twitter][2]", "_node": "dtb4IYrqT4iRzCcOyTHsNg"},
+				{"_index": "twitter", "_type": "tweet", "_id": "2", "_score": 1.0}
+			]
+		}
+	}`
+
+	var response es.SearchResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := 2, len(response.HitsWrapper.Hits); expected != got {
+		t.Fatalf("expected %d hit(s); got %d", expected, got)
+	}
+
+	hit := response.HitsWrapper.Hits[0]
+
+	if expected, got := "dtb4IYrqT4iRzCcOyTHsNg", hit.Node; expected != got {
+		t.Errorf("expected _node = %q; got %q", expected, got)
+	}
+
+	if expected, got := "[twitter][2]", hit.Shard; expected != got {
+		t.Errorf("expected _shard = %q; got %q", expected, got)
+	}
+
+	hit = response.HitsWrapper.Hits[1]
+
+	if expected, got := "", hit.Node; expected != got {
+		t.Errorf("expected _node = %q; got %q", expected, got)
+	}
+}
+
+func TestParseCountResponse(t *testing.T) {
+	body := `{"count": 21474836470, "_shards": {"total": 5, "successful": 4, "failed": 1}}`
+
+	response, err := es.ParseCountResponse(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := int64(21474836470), response.Count; expected != got {
+		t.Errorf("expected count = %d; got %d", expected, got)
+	}
+
+	if expected, got := (es.Shards{Total: 5, Successful: 4, Failed: 1}), response.Shards; expected != got {
+		t.Errorf("expected shards = %+v; got %+v", expected, got)
+	}
+}
+
+func TestValidateQueryResponse(t *testing.T) {
+	for _, tuple := range []struct {
+		body        string
+		valid       bool
+		explanation string
+		error       string
+	}{
+		{
+			body: `{
+				"valid": true,
+				"_shards": {"total": 1, "successful": 1, "failed": 0},
+				"explanations": [{"index": "twitter", "valid": true, "explanation": "user:kimchy"}]
+			}`,
+			valid:       true,
+			explanation: "user:kimchy",
+		},
+		{
+			body: `{
+				"valid": false,
+				"_shards": {"total": 1, "successful": 1, "failed": 0},
+				"explanations": [{"index": "twitter", "valid": false, "error": "org.elasticsearch.index.query.QueryParsingException: [twitter] No query registered for [foo]"}]
+			}`,
+			valid: false,
+			error: "org.elasticsearch.index.query.QueryParsingException: [twitter] No query registered for [foo]",
+		},
+	} {
+		var response es.ValidateQueryResponse
+		if err := json.Unmarshal([]byte(tuple.body), &response); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, got := tuple.valid, response.Valid; expected != got {
+			t.Errorf("expected valid = %v; got %v", expected, got)
+		}
+
+		if expected, got := 1, len(response.Explanations); expected != got {
+			t.Fatalf("expected %d explanation(s); got %d", expected, got)
+		}
+
+		explanation := response.Explanations[0]
+
+		if expected, got := "twitter", explanation.Index; expected != got {
+			t.Errorf("expected index = %q; got %q", expected, got)
+		}
+
+		if expected, got := tuple.valid, explanation.Valid; expected != got {
+			t.Errorf("expected explanation valid = %v; got %v", expected, got)
+		}
+
+		if expected, got := tuple.explanation, explanation.Explanation; expected != got {
+			t.Errorf("expected explanation = %q; got %q", expected, got)
+		}
+
+		if expected, got := tuple.error, explanation.Error; expected != got {
+			t.Errorf("expected error = %q; got %q", expected, got)
+		}
+	}
+}
+
+func TestDecodeHits(t *testing.T) {
+	body := `{
+		"hits": {
+			"total": 2,
+			"hits": [
+				{"_id": "1", "_source": {"user": "kimchy", "message": "trying out Elastic Search"}},
+				{"_id": "2", "_source": {"user": "bob", "message": "hello"}}
+			]
+		}
+	}`
+
+	var response es.SearchResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	type tweet struct {
+		User    string `json:"user"`
+		Message string `json:"message"`
+	}
+
+	tweets, err := es.DecodeHits[tweet](&response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := 2, len(tweets); expected != got {
+		t.Fatalf("expected %d tweet(s); got %d", expected, got)
+	}
+
+	if expected, got := (tweet{"kimchy", "trying out Elastic Search"}), tweets[0]; expected != got {
+		t.Errorf("expected %+v; got %+v", expected, got)
+	}
+
+	if expected, got := (tweet{"bob", "hello"}), tweets[1]; expected != got {
+		t.Errorf("expected %+v; got %+v", expected, got)
+	}
+}
+
+func TestSearchResponseEachWithMeta(t *testing.T) {
+	body := `{
+		"hits": {
+			"total": 3,
+			"hits": [
+				{"_id": "1", "_score": 2.5, "_source": {"user": "kimchy"}},
+				{"_id": "2", "_score": 1.5, "_source": {"user": "bob"}},
+				{"_id": "3", "_score": 0.5, "_source": {"user": "alice"}}
+			]
+		}
+	}`
+
+	var response es.SearchResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	users := map[string]string{}
+	err := response.EachWithMeta(func(hit es.Hit, source json.RawMessage) error {
+		var doc struct {
+			User string `json:"user"`
+		}
+		if err := json.Unmarshal(source, &doc); err != nil {
+			return err
+		}
+		users[hit.ID] = doc.User
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := 3, len(users); expected != got {
+		t.Fatalf("expected %d callback(s); got %d", expected, got)
+	}
+
+	for id, expected := range map[string]string{"1": "kimchy", "2": "bob", "3": "alice"} {
+		if got := users[id]; expected != got {
+			t.Errorf("%s: expected user %q; got %q", id, expected, got)
+		}
+	}
+
+	calls := 0
+	err = response.EachWithMeta(func(hit es.Hit, source json.RawMessage) error {
+		calls++
+		if *hit.Score < 2 {
+			return fmt.Errorf("stop at %s", hit.ID)
+		}
+		return nil
+	})
+
+	if expected, got := "stop at 2", fmt.Sprint(err); expected != got {
+		t.Errorf("expected error %q; got %q", expected, got)
+	}
+
+	if expected, got := 2, calls; expected != got {
+		t.Errorf("expected %d callback(s) before stopping; got %d", expected, got)
+	}
+}
+
+func TestSearchResponseHitVersionAndSeqNo(t *testing.T) {
+	body := `{
+		"hits": {
+			"total": 1,
+			"hits": [
+				{"_id": "1", "_version": 3, "_seq_no": 12, "_primary_term": 2}
+			]
+		}
+	}`
+
+	var response es.SearchResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	hit := response.HitsWrapper.Hits[0]
+
+	if expected, got := int64(3), hit.Version; expected != got {
+		t.Errorf("expected _version = %d; got %d", expected, got)
+	}
+
+	if expected, got := int64(12), hit.SeqNo; expected != got {
+		t.Errorf("expected _seq_no = %d; got %d", expected, got)
+	}
+
+	if expected, got := int64(2), hit.PrimaryTerm; expected != got {
+		t.Errorf("expected _primary_term = %d; got %d", expected, got)
+	}
+}
+
+func TestSearchResponseHitsBySeqNo(t *testing.T) {
+	body := `{
+		"hits": {
+			"total": 3,
+			"hits": [
+				{"_id": "7", "_seq_no": 0, "_primary_term": 1, "sort": [0]},
+				{"_id": "3", "_seq_no": 42, "_primary_term": 1, "sort": [42]},
+				{"_id": "5", "_seq_no": 43, "_primary_term": 1, "sort": [43]}
+			]
+		}
+	}`
+
+	var response es.SearchResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, expected := range []int64{0, 42, 43} {
+		if got := response.HitsWrapper.Hits[i].SeqNo; expected != got {
+			t.Errorf("hit %d: expected _seq_no = %d; got %d", i, expected, got)
+		}
+	}
+}
+
+func TestSearchResponseHitHighlight(t *testing.T) {
+	body := `{
+		"hits": {
+			"total": 1,
+			"hits": [{
+				"_id": "1",
+				"_source": {"message": "trying out Elasticsearch, a search engine"},
+				"highlight": {"message": ["trying out Elasticsearch, a <em>search</em> engine"]}
+			}]
+		}
+	}`
+
+	var response es.SearchResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	fragments := response.HitsWrapper.Hits[0].Highlight["message"]
+	if expected, got := 1, len(fragments); expected != got {
+		t.Fatalf("expected %d fragment(s); got %d", expected, got)
+	}
+
+	if expected, got := "trying out Elasticsearch, a <em>search</em> engine", fragments[0]; expected != got {
+		t.Errorf("expected fragment %q; got %q", expected, got)
+	}
+}
+
+func TestSearchResponseAggregation(t *testing.T) {
+	body := `{
+		"aggregations": {
+			"sterms#tags": {"buckets": [{"key": "go", "doc_count": 2}]},
+			"avg_likes": {"value": 4.5}
+		}
+	}`
+
+	var response es.SearchResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tuple := range []struct {
+		name string
+		typ  string
+		raw  string
+	}{
+		{"tags", "sterms", `{"buckets": [{"key": "go", "doc_count": 2}]}`},
+		{"avg_likes", "", `{"value": 4.5}`},
+	} {
+		raw, typ, ok := response.Aggregation(tuple.name)
+		if !ok {
+			t.Errorf("%s: expected to find aggregation", tuple.name)
+			continue
+		}
+
+		if expected, got := tuple.typ, typ; expected != got {
+			t.Errorf("%s: expected type = %q; got %q", tuple.name, expected, got)
+		}
+
+		if expected, got := tuple.raw, string(raw); expected != got {
+			t.Errorf("%s: expected %s; got %s", tuple.name, expected, got)
+		}
+	}
+
+	if _, _, ok := response.Aggregation("missing"); ok {
+		t.Errorf("expected no aggregation named missing")
+	}
+}
+
+func TestSearchResponseBuckets(t *testing.T) {
+	body := `{
+		"aggregations": {
+			"tags": {
+				"doc_count_error_upper_bound": 0,
+				"sum_other_doc_count": 0,
+				"buckets": [{"key": "go", "doc_count": 7}, {"key": "search", "doc_count": 3}]
+			},
+			"date_histogram#by_day": {
+				"buckets": [{"key_as_string": "2009-11-15T00:00:00.000Z", "key": 1258243200000, "doc_count": 2}]
+			}
+		}
+	}`
+
+	var response es.SearchResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err := response.Buckets("tags")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := 2, len(tags); expected != got {
+		t.Fatalf("expected %d bucket(s); got %d", expected, got)
+	}
+
+	if expected, got := "go", tags[0].Key; expected != got {
+		t.Errorf("expected key %v; got %v", expected, got)
+	}
+
+	if expected, got := int64(7), tags[0].DocCount; expected != got {
+		t.Errorf("expected doc_count %d; got %d", expected, got)
+	}
+
+	days, err := response.Buckets("by_day")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "2009-11-15T00:00:00.000Z", days[0].KeyAsString; expected != got {
+		t.Errorf("expected key_as_string %q; got %q", expected, got)
+	}
+
+	if _, err := response.Buckets("missing"); err == nil {
+		t.Errorf("expected an error for a missing aggregation")
+	}
+}
+
+func TestSearchResponseTookDuration(t *testing.T) {
+	response := es.SearchResponse{Took: 1500}
+
+	if expected, got := 1500*time.Millisecond, response.TookDuration(); expected != got {
+		t.Errorf("expected %s; got %s", expected, got)
+	}
+}
+
+func TestSearchResponseExtra(t *testing.T) {
+	body := `{
+		"took": 3,
+		"timed_out": false,
+		"hits": {"total": 0, "hits": []},
+		"pit_id": "46ToAwMDaWR5"
+	}`
+
+	var plain es.SearchResponse
+	if err := json.Unmarshal([]byte(body), &plain); err != nil {
+		t.Fatal(err)
+	}
+
+	if plain.Extra != nil {
+		t.Errorf("expected no extra fields from a plain decode; got %v", plain.Extra)
+	}
+
+	response, err := es.ParseSearchResponseWithExtra(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := 3, response.Took; expected != got {
+		t.Errorf("expected took = %d; got %d", expected, got)
+	}
+
+	if expected, got := 1, len(response.Extra); expected != got {
+		t.Fatalf("expected %d extra field(s); got %d (%v)", expected, got, response.Extra)
+	}
+
+	if expected, got := `"46ToAwMDaWR5"`, string(response.Extra["pit_id"]); expected != got {
+		t.Errorf("expected extra pit_id = %s; got %s", expected, got)
+	}
+}
+
+func TestSearchResponseCompletionOptions(t *testing.T) {
+	body := `{
+		"took": 2,
+		"hits": {"total": 0, "hits": []},
+		"suggest": {
+			"song-suggest": [{
+				"text": "nir",
+				"offset": 0,
+				"length": 3,
+				"options": [
+					{"text": "Nirvana", "_index": "music", "_id": "1", "_score": 1.0},
+					{"text": "Nirvana Unplugged", "_index": "music", "_id": "2", "_score": 1.0}
+				]
+			}]
+		}
+	}`
+
+	var response es.SearchResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	options, err := response.CompletionOptions("song-suggest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "Nirvana,Nirvana Unplugged", strings.Join(options, ","); expected != got {
+		t.Errorf("expected options %q; got %q", expected, got)
+	}
+
+	if _, err := response.CompletionOptions("missing"); err == nil {
+		t.Errorf("expected an error for a missing suggester")
+	}
+}
+
+func TestSearchResponseNumHits(t *testing.T) {
+	for _, tuple := range []struct {
+		body          string
+		expectedHits  int
+		expectedEmpty bool
+	}{
+		{
+			body:          `{"took":1,"hits":{"total":0,"hits":[]}}`,
+			expectedHits:  0,
+			expectedEmpty: true,
+		},
+		{
+			body:          `{"took":1,"hits":{"total":12,"hits":[{"_id":"1"},{"_id":"2"}]}}`,
+			expectedHits:  2,
+			expectedEmpty: false,
+		},
+	} {
+		var response es.SearchResponse
+		if err := json.Unmarshal([]byte(tuple.body), &response); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, got := tuple.expectedHits, response.NumHits(); expected != got {
+			t.Errorf("%s: expected %d hit(s); got %d", tuple.body, expected, got)
+		}
+
+		if expected, got := tuple.expectedEmpty, response.IsEmpty(); expected != got {
+			t.Errorf("%s: expected empty = %v; got %v", tuple.body, expected, got)
+		}
+	}
+}
+
+func TestSearchResponseProfile(t *testing.T) {
+	body := `{
+		"took": 25,
+		"hits": {"total": 1, "hits": []},
+		"profile": {
+			"shards": [{
+				"id": "[q2aE02wS1R8qQFnYu6vDVQ][twitter][0]",
+				"searches": [{
+					"query": [{
+						"type": "BooleanQuery",
+						"description": "message:get message:search",
+						"time_in_nanos": 1873811,
+						"breakdown": {"score": 51306, "create_weight": 1011, "next_doc": 63549},
+						"children": [
+							{"type": "TermQuery", "description": "message:get", "time_in_nanos": 36631, "breakdown": {"score": 4617}},
+							{"type": "TermQuery", "description": "message:search", "time_in_nanos": 1780123, "breakdown": {"score": 1213}}
+						]
+					}],
+					"rewrite_time": 51443,
+					"collector": [{
+						"name": "SimpleTopScoreDocCollector",
+						"reason": "search_top_hits",
+						"time_in_nanos": 32273,
+						"children": [{"name": "MultiCollector", "reason": "search_multi", "time_in_nanos": 1231}]
+					}]
+				}],
+				"aggregations": [{
+					"type": "LongTermsAggregator",
+					"description": "my_scoped_agg",
+					"time_in_nanos": 79294,
+					"breakdown": {"reduce": 0, "build_aggregation": 30885, "collect": 45786}
+				}]
+			}]
+		}
+	}`
+
+	response, err := es.ParseSearchResponseWithExtra(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Profile == nil || len(response.Profile.Shards) != 1 {
+		t.Fatalf("expected a profile of 1 shard; got %+v", response.Profile)
+	}
+
+	shard := response.Profile.Shards[0]
+	if expected, got := "[q2aE02wS1R8qQFnYu6vDVQ][twitter][0]", shard.ID; expected != got {
+		t.Errorf("expected shard ID %q; got %q", expected, got)
+	}
+
+	if len(shard.Searches) != 1 || len(shard.Searches[0].Query) != 1 {
+		t.Fatalf("expected 1 search of 1 query; got %+v", shard.Searches)
+	}
+
+	query := shard.Searches[0].Query[0]
+	if expected, got := "BooleanQuery", query.Type; expected != got {
+		t.Errorf("expected query type %q; got %q", expected, got)
+	}
+
+	if expected, got := int64(51306), query.Breakdown["score"]; expected != got {
+		t.Errorf("expected score time %d; got %d", expected, got)
+	}
+
+	// Find the slowest child.
+	var slowest es.QueryProfile
+	for _, child := range query.Children {
+		if child.TimeInNanos > slowest.TimeInNanos {
+			slowest = child
+		}
+	}
+
+	if expected, got := "message:search", slowest.Description; expected != got {
+		t.Errorf("expected the slowest child to be %q; got %q", expected, got)
+	}
+
+	if expected, got := 1780123*time.Nanosecond, slowest.Time(); expected != got {
+		t.Errorf("expected time %s; got %s", expected, got)
+	}
+
+	if expected, got := int64(51443), shard.Searches[0].RewriteTime; expected != got {
+		t.Errorf("expected rewrite time %d; got %d", expected, got)
+	}
+
+	collector := shard.Searches[0].Collector[0]
+	if expected, got := "search_top_hits", collector.Reason; expected != got {
+		t.Errorf("expected collector reason %q; got %q", expected, got)
+	}
+
+	if expected, got := "MultiCollector", collector.Children[0].Name; expected != got {
+		t.Errorf("expected child collector %q; got %q", expected, got)
+	}
+
+	if expected, got := int64(30885), shard.Aggregations[0].Breakdown["build_aggregation"]; expected != got {
+		t.Errorf("expected build_aggregation time %d; got %d", expected, got)
+	}
+
+	if len(response.Extra) != 0 {
+		t.Errorf("expected no extra fields; got %v", response.Extra)
+	}
+}
+
+func TestParseSearchResponse(t *testing.T) {
+	for _, tuple := range []struct {
+		version          string
+		body             string
+		expectedTotal    int
+		expectedRelation string
+		expectedMaxScore *float64
+	}{
+		{
+			version: "1.x",
+			body: `{
+				"took": 5,
+				"timed_out": false,
+				"_shards": {"total": 5, "successful": 5, "failed": 0},
+				"hits": {
+					"total": 2,
+					"max_score": 1.3862944,
+					"hits": [
+						{"_index": "twitter", "_type": "tweet", "_id": "1", "_score": 1.3862944, "_source": {"user": "kimchy"}},
+						{"_index": "twitter", "_type": "tweet", "_id": "2", "_score": 0.5, "_source": {"user": "bob"}}
+					]
+				}
+			}`,
+			expectedTotal:    2,
+			expectedRelation: "eq",
+			expectedMaxScore: float64Ptr(1.3862944),
+		},
+		{
+			version: "7.x",
+			body: `{
+				"took": 5,
+				"timed_out": false,
+				"_shards": {"total": 1, "successful": 1, "skipped": 0, "failed": 0},
+				"hits": {
+					"total": {"value": 10000, "relation": "gte"},
+					"max_score": null,
+					"hits": [
+						{"_index": "twitter", "_type": "_doc", "_id": "1", "_score": null, "_source": {"user": "kimchy"}, "sort": [1258294332000]},
+						{"_index": "twitter", "_type": "_doc", "_id": "2", "_score": null, "_source": {"user": "bob"}, "sort": [1258294331000]}
+					]
+				}
+			}`,
+			expectedTotal:    10000,
+			expectedRelation: "gte",
+			expectedMaxScore: nil,
+		},
+	} {
+		response, err := es.ParseSearchResponse(strings.NewReader(tuple.body))
+		if err != nil {
+			t.Fatalf("%s: %s", tuple.version, err)
+		}
+
+		if expected, got := 5, response.Took; expected != got {
+			t.Errorf("%s: expected took = %d; got %d", tuple.version, expected, got)
+		}
+
+		if expected, got := response.Shards.Total, response.Shards.Successful; expected != got || got == 0 {
+			t.Errorf("%s: expected all %d shard(s) successful; got %d", tuple.version, expected, got)
+		}
+
+		if expected, got := tuple.expectedTotal, response.HitsWrapper.Total; expected != got {
+			t.Errorf("%s: expected total = %d; got %d", tuple.version, expected, got)
+		}
+
+		if expected, got := tuple.expectedRelation, response.HitsWrapper.Relation; expected != got {
+			t.Errorf("%s: expected relation = %q; got %q", tuple.version, expected, got)
+		}
+
+		if expected, got := tuple.expectedMaxScore, response.HitsWrapper.MaxScore; (expected == nil) != (got == nil) || (got != nil && *expected != *got) {
+			t.Errorf("%s: expected max_score = %v; got %v", tuple.version, expected, got)
+		}
+
+		if expected, got := 2, response.NumHits(); expected != got {
+			t.Fatalf("%s: expected %d hit(s); got %d", tuple.version, expected, got)
+		}
+
+		hit := response.HitsWrapper.Hits[0]
+		if expected, got := "twitter/1", hit.Index+"/"+hit.ID; expected != got {
+			t.Errorf("%s: expected hit %s; got %s", tuple.version, expected, got)
+		}
+
+		if expected, got := `{"user": "kimchy"}`, string(hit.Source); expected != got {
+			t.Errorf("%s: expected source %s; got %s", tuple.version, expected, got)
+		}
+
+		withExtra, err := es.ParseSearchResponseWithExtra(strings.NewReader(tuple.body))
+		if err != nil {
+			t.Fatalf("%s: %s", tuple.version, err)
+		}
+
+		if len(withExtra.Extra) != 0 {
+			t.Errorf("%s: expected no extra fields; got %v", tuple.version, withExtra.Extra)
+		}
+	}
+}
+
+func TestSearchResponseRestTotalHitsAsInt(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Write([]byte(`{"took":1,"timed_out":false,"hits":{"total":12000,"max_score":1.0,"hits":[]}}`))
+	}))
+	defer server.Close()
+
+	var response es.SearchResponse
+	if _, err := es.NewNode(server.URL, time.Second).Fire(es.SearchRequest{
+		Params: es.SearchParams{Indices: []string{"twitter"}, RestTotalHitsAsInt: "true"},
+	}, &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "rest_total_hits_as_int=true", query; expected != got {
+		t.Errorf("expected query %q; got %q", expected, got)
+	}
+
+	if expected, got := 12000, response.HitsWrapper.Total; expected != got {
+		t.Errorf("expected total = %d; got %d", expected, got)
+	}
+
+	if expected, got := "eq", response.HitsWrapper.Relation; expected != got {
+		t.Errorf("expected relation = %q; got %q", expected, got)
+	}
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,158 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+// http://www.elasticsearch.org/guide/reference/api/search/scroll.html
+//
+// A ScrollRequest fetches the next page of a scroll, started by a search with
+// SearchParams.Scroll set. Its response is a SearchResponse, whose ScrollID
+// is used for the following page.
+type ScrollRequest struct {
+	ScrollID string
+	Scroll   string // keep-alive, eg. "1m"
+	Params   CommonParams
+}
+
+func (r ScrollRequest) EncodeSource(enc *json.Encoder) error {
+	return enc.Encode(map[string]string{
+		"scroll":    r.Scroll,
+		"scroll_id": r.ScrollID,
+	})
+}
+
+func (r ScrollRequest) Request(uri *url.URL) (*http.Request, error) {
+	uri.Path = "/_search/scroll"
+	uri.RawQuery = r.Params.Values().Encode()
+
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+
+	if err := r.EncodeSource(enc); err != nil {
+		return nil, err
+	}
+
+	return http.NewRequest("POST", uri.String(), buf)
+}
+
+// ClearScrollRequest frees the search contexts of scrolls before their
+// keep-alives expire.
+type ClearScrollRequest struct {
+	ScrollIDs []string
+	Params    CommonParams
+}
+
+func (r ClearScrollRequest) EncodeSource(enc *json.Encoder) error {
+	return enc.Encode(map[string][]string{
+		"scroll_id": r.ScrollIDs,
+	})
+}
+
+func (r ClearScrollRequest) Request(uri *url.URL) (*http.Request, error) {
+	uri.Path = "/_search/scroll"
+	uri.RawQuery = r.Params.Values().Encode()
+
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+
+	if err := r.EncodeSource(enc); err != nil {
+		return nil, err
+	}
+
+	return http.NewRequest("DELETE", uri.String(), buf)
+}
+
+//
+//
+//
+
+// A Scroller iterates over every hit of a search, a page at a time, firing
+// the initial search and then each scroll request as needed.
+//
+//	s := NewScroller(cluster, request, "1m")
+//	defer s.Close()
+//	for s.Next() {
+//	    for _, hit := range s.Hits() { ... }
+//	}
+//	if err := s.Err(); err != nil { ... }
+type Scroller struct {
+	firer    Firer
+	request  SearchRequest
+	scroll   string
+	scrollID string
+	hits     []Hit
+	done     bool
+	err      error
+}
+
+// NewScroller returns a Scroller over the results of the search, which keeps
+// the scroll alive for the given duration, eg. "1m", between pages.
+func NewScroller(f Firer, r SearchRequest, scroll string) *Scroller {
+	r.Params.Scroll = scroll
+	return &Scroller{
+		firer:   f,
+		request: r,
+		scroll:  scroll,
+	}
+}
+
+// Next fetches the next page of hits, and reports whether it has any. It
+// returns false once the hits are exhausted, or after an error.
+func (s *Scroller) Next() bool {
+	if s.done {
+		return false
+	}
+
+	var f Fireable = s.request
+	if s.scrollID != "" {
+		f = ScrollRequest{ScrollID: s.scrollID, Scroll: s.scroll}
+	}
+
+	var response SearchResponse
+	if _, err := s.firer.Fire(f, &response); err != nil {
+		s.err, s.done, s.hits = err, true, nil
+		return false
+	}
+
+	if response.ScrollID != "" {
+		s.scrollID = response.ScrollID
+	}
+
+	s.hits = response.HitsWrapper.Hits
+	if len(s.hits) == 0 {
+		s.done = true
+		return false
+	}
+
+	return true
+}
+
+// Hits returns the current page of hits.
+func (s *Scroller) Hits() []Hit {
+	return s.hits
+}
+
+// Err returns the error, if any, which stopped the Scroller.
+func (s *Scroller) Err() error {
+	return s.err
+}
+
+// Close clears the scroll, freeing its search context without waiting for
+// the keep-alive to expire.
+func (s *Scroller) Close() error {
+	s.done = true
+	if s.scrollID == "" {
+		return nil
+	}
+
+	scrollID := s.scrollID
+	s.scrollID = ""
+
+	var response struct{}
+	_, err := s.firer.Fire(ClearScrollRequest{ScrollIDs: []string{scrollID}}, &response)
+	return err
+}
@@ -0,0 +1,118 @@
+package elasticsearch_test
+
+import (
+	"encoding/json"
+	"fmt"
+	es "github.com/peterbourgon/elasticsearch"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSearchParamsScroll(t *testing.T) {
+	request, err := es.SearchRequest{
+		Params: es.SearchParams{Indices: []string{"twitter"}, Scroll: "1m"},
+	}.Request(&url.URL{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "scroll=1m", request.URL.RawQuery; expected != got {
+		t.Errorf("expected query = %q; got %q", expected, got)
+	}
+}
+
+func TestScrollRequest(t *testing.T) {
+	request, err := es.ScrollRequest{ScrollID: "c2Nhbjs2OzM0NDg1ODpzRlBLc0FXNlNyNm5JWUc1", Scroll: "1m"}.Request(&url.URL{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "POST /_search/scroll", request.Method+" "+request.URL.Path; expected != got {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+
+	got, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `{"scroll":"1m","scroll_id":"c2Nhbjs2OzM0NDg1ODpzRlBLc0FXNlNyNm5JWUc1"}` + "\n"; expected != string(got) {
+		t.Errorf("expected body = %s; got %s", expected, got)
+	}
+}
+
+func TestScroller(t *testing.T) {
+	pages := [][]string{{"1", "2"}, {"3", "4"}, {"5"}, {}}
+	var requests, cleared []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ScrollID interface{} `json:"scroll_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		if r.Method == "DELETE" {
+			cleared = append(cleared, fmt.Sprint(body.ScrollID))
+			w.Write([]byte(`{"succeeded":true}`))
+			return
+		}
+
+		page := len(requests)
+		requests = append(requests, fmt.Sprintf("%s %s scroll=%s id=%v", r.Method, r.URL.Path, r.URL.Query().Get("scroll"), body.ScrollID))
+
+		hits := []map[string]string{}
+		for _, id := range pages[page] {
+			hits = append(hits, map[string]string{"_id": id})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"_scroll_id": fmt.Sprintf("scroll-%d", page),
+			"hits":       map[string]interface{}{"total": 5, "hits": hits},
+		})
+	}))
+	defer server.Close()
+
+	node := es.NewNode(server.URL, time.Second)
+	s := es.NewScroller(node, es.SearchRequest{Params: es.SearchParams{Indices: []string{"twitter"}}}, "1m")
+
+	var ids []string
+	for s.Next() {
+		for _, hit := range s.Hits() {
+			ids = append(ids, hit.ID)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "[1 2 3 4 5]", fmt.Sprint(ids); expected != got {
+		t.Errorf("expected ids %s; got %s", expected, got)
+	}
+
+	expected := []string{
+		"GET /twitter/_search scroll=1m id=<nil>",
+		"POST /_search/scroll scroll= id=scroll-0",
+		"POST /_search/scroll scroll= id=scroll-1",
+		"POST /_search/scroll scroll= id=scroll-2",
+	}
+	if fmt.Sprint(expected) != fmt.Sprint(requests) {
+		t.Errorf("expected requests:\n%q\ngot:\n%q", expected, requests)
+	}
+
+	if expected, got := "[[scroll-3]]", fmt.Sprint(cleared); expected != got {
+		t.Errorf("expected cleared scroll ids %s; got %s", expected, got)
+	}
+
+	if s.Next() {
+		t.Errorf("expected no more pages after Close")
+	}
+}
@@ -10,3 +10,9 @@ type Searcher interface {
 type MultiSearcher interface {
 	MultiSearch(MultiSearchRequest) (MultiSearchResponse, error)
 }
+
+// Firer is the interface that wraps the Fire method, implemented by both Node
+// and Cluster.
+type Firer interface {
+	Fire(f Fireable, v interface{}) (*Response, error)
+}
@@ -0,0 +1,88 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// http://www.elasticsearch.org/guide/reference/api/search/template.html
+//
+// A SearchTemplateRequest searches with a stored template, named by Id, or an
+// inline template Source, rendered with TemplateParams.
+type SearchTemplateRequest struct {
+	Params         SearchParams
+	Id             string
+	Source         interface{} // a string, or an object like a SearchRequest Query
+	TemplateParams map[string]interface{}
+}
+
+func (r SearchTemplateRequest) EncodeMultiHeader(enc *json.Encoder) error {
+	return enc.Encode(r.Params)
+}
+
+func (r SearchTemplateRequest) EncodeQuery(enc *json.Encoder) error {
+	if (r.Id == "") == (r.Source == nil) {
+		return fmt.Errorf("search template: exactly one of id and source is required")
+	}
+
+	return enc.Encode(struct {
+		Id     string                 `json:"id,omitempty"`
+		Source interface{}            `json:"source,omitempty"`
+		Params map[string]interface{} `json:"params,omitempty"`
+	}{
+		Id:     r.Id,
+		Source: r.Source,
+		Params: r.TemplateParams,
+	})
+}
+
+func (r SearchTemplateRequest) Path() string {
+	return SearchRequest{Params: r.Params}.Path() + "/template"
+}
+
+func (r SearchTemplateRequest) Request(uri *url.URL) (*http.Request, error) {
+	uri.Path = r.Path()
+	uri.RawQuery = r.Params.Values().Encode()
+
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+
+	if err := r.EncodeQuery(enc); err != nil {
+		return nil, err
+	}
+
+	return http.NewRequest("POST", uri.String(), buf)
+}
+
+//
+//
+//
+
+// MultiSearchTemplateRequest is the MultiSearchRequest of templated searches.
+// Its response is a MultiSearchResponse.
+type MultiSearchTemplateRequest struct {
+	Params   MultiSearchParams
+	Requests []SearchTemplateRequest
+}
+
+func (r MultiSearchTemplateRequest) Request(uri *url.URL) (*http.Request, error) {
+	uri.Path = "/_msearch/template"
+	uri.RawQuery = r.Params.Values().Encode()
+
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+
+	for i, req := range r.Requests {
+		if err := req.EncodeMultiHeader(enc); err != nil {
+			return nil, fmt.Errorf("request %d: %s", i, err)
+		}
+		if err := req.EncodeQuery(enc); err != nil {
+			return nil, fmt.Errorf("request %d: %s", i, err)
+		}
+	}
+
+	return http.NewRequest("POST", uri.String(), buf)
+}
@@ -0,0 +1,88 @@
+package elasticsearch_test
+
+import (
+	es "github.com/peterbourgon/elasticsearch"
+	"io/ioutil"
+	"net/url"
+	"testing"
+)
+
+func TestSearchTemplateRequest(t *testing.T) {
+	request, err := es.SearchTemplateRequest{
+		Params:         es.SearchParams{Indices: []string{"twitter"}},
+		Id:             "by-user",
+		TemplateParams: map[string]interface{}{"user": "kimchy"},
+	}.Request(&url.URL{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "/twitter/_search/template", request.URL.Path; expected != got {
+		t.Errorf("expected path = %q; got %q", expected, got)
+	}
+
+	got, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `{"id":"by-user","params":{"user":"kimchy"}}` + "\n"; expected != string(got) {
+		t.Errorf("expected body = %s; got %s", expected, got)
+	}
+}
+
+func TestSearchTemplateRequestIdOrSource(t *testing.T) {
+	for _, r := range []es.SearchTemplateRequest{
+		{},
+		{Id: "by-user", Source: `{"query":{"match_all":{}}}`},
+	} {
+		if _, err := r.Request(&url.URL{}); err == nil {
+			t.Errorf("%v: expected an error", r)
+		}
+	}
+}
+
+func TestMultiSearchTemplateRequest(t *testing.T) {
+	request, err := es.MultiSearchTemplateRequest{
+		Requests: []es.SearchTemplateRequest{
+			{
+				Params:         es.SearchParams{Indices: []string{"twitter"}},
+				Id:             "by-user",
+				TemplateParams: map[string]interface{}{"user": "kimchy"},
+			},
+			{
+				Params: es.SearchParams{Indices: []string{"blog"}, Routing: "r1"},
+				Source: map[string]interface{}{
+					"query": map[string]interface{}{
+						"match": map[string]string{"title": "{{title}}"},
+					},
+				},
+				TemplateParams: map[string]interface{}{"title": "go"},
+			},
+		},
+	}.Request(&url.URL{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, got := "/_msearch/template", request.URL.Path; expected != got {
+		t.Errorf("expected path = %q; got %q", expected, got)
+	}
+
+	expected := `{"index":["twitter"]}
+{"id":"by-user","params":{"user":"kimchy"}}
+{"index":["blog"],"routing":"r1"}
+{"source":{"query":{"match":{"title":"{{title}}"}}},"params":{"title":"go"}}
+`
+
+	got, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected != string(got) {
+		t.Errorf("Body: expected:\n---\n%s\n---\ngot:\n---\n%s\n---\n", expected, got)
+	}
+}
@@ -2,6 +2,10 @@ package elasticsearch
 
 import (
 	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 // This file contains structures that represent all of the various JSON-
@@ -12,6 +16,121 @@ type SubQuery interface{}
 
 var nilSubQuery SubQuery
 
+// expensiveQueries are the query types which ElasticSearch rejects when
+// search.allow_expensive_queries is false.
+var expensiveQueries = map[string]bool{
+	"fuzzy":        true,
+	"has_child":    true,
+	"has_parent":   true,
+	"parent_id":    true,
+	"percolate":    true,
+	"prefix":       true,
+	"regexp":       true,
+	"script":       true,
+	"script_score": true,
+	"wildcard":     true,
+}
+
+// compoundQueries are, for each query type which contains other queries, the
+// keys within it holding a query, or an array of them. A dotted key, eg.
+// "functions.filter", is a path through objects, or arrays of them. Any other
+// keys, eg. field names, aren't checked for expensive queries.
+var compoundQueries = map[string][]string{
+	"and":            {"filters"},
+	"bool":           {"must", "must_not", "should", "filter"},
+	"boosting":       {"positive", "negative"},
+	"constant_score": {"filter", "query"},
+	"custom_score":   {"query"},
+	"dis_max":        {"queries"},
+	"filtered":       {"query", "filter"},
+	"fquery":         {"query"},
+	"function_score": {"query", "filter", "functions.filter"},
+	"has_child":      {"query", "filter"},
+	"has_parent":     {"query", "filter"},
+	"nested":         {"query", "filter"},
+	"not":            {"filter", "query"},
+	"or":             {"filters"},
+	"script_score":   {"query"},
+}
+
+// lookup returns the value at the dotted path within v, taking each element
+// of any arrays along the way, eg. the filter of each of a function_score's
+// functions.
+func lookup(v interface{}, path string) interface{} {
+	key, rest := path, ""
+	if i := strings.Index(path, "."); i >= 0 {
+		key, rest = path[:i], path[i+1:]
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if rest == "" {
+			return v[key]
+		}
+		return lookup(v[key], rest)
+	case []interface{}:
+		a := []interface{}{}
+		for _, value := range v {
+			a = append(a, lookup(value, path))
+		}
+		return a
+	}
+	return nil
+}
+
+// ExpensiveQueries returns the types of any expensive queries, eg. wildcard
+// or script, within q. They fail on clusters which disallow expensive queries.
+func ExpensiveQueries(q SubQuery) ([]string, error) {
+	buf, err := json.Marshal(q)
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return nil, err
+	}
+
+	found := map[string]bool{}
+	var walk func(interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for typ, body := range v {
+				if expensiveQueries[typ] {
+					found[typ] = true
+				}
+
+				if typ == "query" {
+					walk(body)
+					continue
+				}
+
+				switch body := body.(type) {
+				case map[string]interface{}:
+					for _, key := range compoundQueries[typ] {
+						walk(lookup(body, key))
+					}
+				case []interface{}:
+					walk(body) // eg. {"and": [...]}
+				}
+			}
+		case []interface{}:
+			for _, value := range v {
+				walk(value)
+			}
+		}
+	}
+	walk(v)
+
+	a := []string{}
+	for key := range found {
+		a = append(a, key)
+	}
+	sort.Strings(a)
+	return a, nil
+}
+
 //
 //
 //
@@ -85,6 +204,11 @@ func MatchQuery(p MatchQueryParams) SubQuery {
 	return p
 }
 
+// Match is shorthand for a MatchQuery of text against a single field.
+func Match(field, text string) SubQuery {
+	return MatchQuery(MatchQueryParams{Query: &Wrapper{Name: field, Wrapped: text}})
+}
+
 //
 //
 //
@@ -99,6 +223,11 @@ func TermQuery(p TermQueryParams) SubQuery {
 	return p
 }
 
+// Term is shorthand for a TermQuery of a single field's exact value.
+func Term(field string, value interface{}) SubQuery {
+	return TermQuery(TermQueryParams{Query: &Wrapper{Name: field, Wrapped: value}})
+}
+
 //
 //
 //
@@ -114,6 +243,27 @@ func TermsQuery(p TermsQueryParams) SubQuery {
 	return p
 }
 
+// http://www.elasticsearch.org/guide/reference/query-dsl/terms-filter.html
+// The terms are fetched from the Path of the document identified by Index,
+// Type, and Id.
+type TermsLookupParams struct {
+	Index   string `json:"index,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Id      string `json:"id"`
+	Path    string `json:"path"`
+	Routing string `json:"routing,omitempty"`
+}
+
+func TermsLookup(field string, p TermsLookupParams) SubQuery {
+	return &Wrapper{
+		Name: "terms",
+		Wrapped: &Wrapper{
+			Name:    field,
+			Wrapped: p,
+		},
+	}
+}
+
 //
 //
 //
@@ -138,11 +288,39 @@ func DisMaxQuery(p DisMaxQueryParams) SubQuery {
 
 // http://www.elasticsearch.org/guide/reference/query-dsl/bool-query.html
 type BoolQueryParams struct {
-	Must                     SubQuery `json:"must,omitempty"` // can be slice!
-	Should                   SubQuery `json:"should,omitempty"`
-	MustNot                  SubQuery `json:"must_not,omitempty"`
-	MinimumNumberShouldMatch int      `json:"minimum_number_should_match,omitempty"`
-	Boost                    float32  `json:"boost,omitempty"`
+	Must                     SubQuery           `json:"must,omitempty"` // can be slice!
+	Should                   SubQuery           `json:"should,omitempty"`
+	MustNot                  SubQuery           `json:"must_not,omitempty"`
+	MinimumNumberShouldMatch int                `json:"minimum_number_should_match,omitempty"`
+	MinimumShouldMatch       MinimumShouldMatch `json:"minimum_should_match,omitempty"`
+	Boost                    float32            `json:"boost,omitempty"`
+}
+
+// MinimumShouldMatch is the number, eg. "2" or "-1", or the percentage, eg.
+// "75%", of should clauses which must match, or a combination of them which
+// depends on the number of clauses, eg. "3<90%" or "2<-25% 9<-3". It fails to
+// marshal otherwise.
+type MinimumShouldMatch string
+
+func (m MinimumShouldMatch) MarshalJSON() ([]byte, error) {
+	specs := strings.Fields(string(m))
+	valid := len(specs) > 0
+	for _, spec := range specs {
+		if i := strings.Index(spec, "<"); i >= 0 {
+			n, err := strconv.Atoi(spec[:i])
+			valid = valid && err == nil && n >= 0
+			spec = spec[i+1:]
+		} else {
+			valid = valid && len(specs) == 1
+		}
+		_, err := strconv.Atoi(strings.TrimSuffix(spec, "%"))
+		valid = valid && err == nil
+	}
+
+	if !valid {
+		return nil, fmt.Errorf("invalid minimum_should_match %q", string(m))
+	}
+	return json.Marshal(string(m))
 }
 
 func BoolQuery(p BoolQueryParams) SubQuery {
@@ -152,6 +330,22 @@ func BoolQuery(p BoolQueryParams) SubQuery {
 	}
 }
 
+// Bool is shorthand for a BoolQuery of the given clauses. Empty clauses are
+// left out.
+func Bool(must, should, mustNot []SubQuery) SubQuery {
+	var p BoolQueryParams
+	if len(must) > 0 {
+		p.Must = must
+	}
+	if len(should) > 0 {
+		p.Should = should
+	}
+	if len(mustNot) > 0 {
+		p.MustNot = mustNot
+	}
+	return BoolQuery(p)
+}
+
 //
 //
 //
@@ -192,6 +386,22 @@ func ConstantScoreQuery(p ConstantScoreQueryParams) SubQuery {
 //
 //
 
+// http://www.elasticsearch.org/guide/reference/query-dsl/range-query.html
+// The opts are eg. {"gte": 10, "lt": 20}, or {"gte": "now-1d/d"} for dates.
+func Range(field string, opts map[string]interface{}) SubQuery {
+	return &Wrapper{
+		Name: "range",
+		Wrapped: &Wrapper{
+			Name:    field,
+			Wrapped: opts,
+		},
+	}
+}
+
+//
+//
+//
+
 func MatchAllQuery() SubQuery {
 	return &Wrapper{
 		Name:    "match_all",
@@ -203,6 +413,30 @@ func MatchAllQuery() SubQuery {
 //
 //
 
+// http://www.elasticsearch.org/guide/reference/query-dsl/exists-query.html
+func ExistsQuery(field string) SubQuery {
+	return &Wrapper{
+		Name:    "exists",
+		Wrapped: map[string]string{"field": field},
+	}
+}
+
+// HasField matches documents with a non-null value for the field.
+func HasField(field string) SubQuery {
+	return ExistsQuery(field)
+}
+
+// MissingField matches documents without a non-null value for the field.
+func MissingField(field string) SubQuery {
+	return BoolQuery(BoolQueryParams{
+		MustNot: ExistsQuery(field),
+	})
+}
+
+//
+//
+//
+
 // Haven't quite figured out how to best represent this.
 // TODO break these up into embeddable query-parts?
 type OffsetLimitFacetsFilterQueryParams struct {
@@ -399,3 +633,71 @@ func NamedFacet(name string, q FacetSubQuery) FacetSubQuery {
 		Wrapped: q,
 	}
 }
+
+//
+//
+//
+// =============================================================================
+// HERE BE AGGREGATIONS
+// =============================================================================
+//
+//
+//
+
+// TermsAgg buckets documents by the values of field, returning the size most
+// frequent. A size of 0 leaves the default.
+func TermsAgg(field string, size int) SubQuery {
+	p := map[string]interface{}{"field": field}
+	if size > 0 {
+		p["size"] = size
+	}
+
+	return &Wrapper{
+		Name:    "terms",
+		Wrapped: p,
+	}
+}
+
+// calendarIntervals are the intervals of a date_histogram which vary in
+// length, eg. with daylight saving time or the month.
+var calendarIntervals = map[string]bool{
+	"minute": true, "1m": true,
+	"hour": true, "1h": true,
+	"day": true, "1d": true,
+	"week": true, "1w": true,
+	"month": true, "1M": true,
+	"quarter": true, "1q": true,
+	"year": true, "1y": true,
+}
+
+// DateHistogramAgg buckets documents by the date in field. An interval of a
+// single calendar unit, eg. "1d" or "month", is a calendar_interval; any
+// other, eg. "90m", is a fixed_interval. Those need ElasticSearch 7.2 or
+// later; use LegacyDateHistogramAgg for older versions.
+func DateHistogramAgg(field, interval string) SubQuery {
+	key := "fixed_interval"
+	if calendarIntervals[interval] {
+		key = "calendar_interval"
+	}
+
+	return &Wrapper{
+		Name: "date_histogram",
+		Wrapped: map[string]string{
+			"field": field,
+			key:     interval,
+		},
+	}
+}
+
+// LegacyDateHistogramAgg is DateHistogramAgg for versions of ElasticSearch
+// before 7.2, which take any interval as the plain interval parameter. Newer
+// versions deprecate it, and 8.0 removes it.
+func LegacyDateHistogramAgg(field, interval string) SubQuery {
+	return &Wrapper{
+		Name: "date_histogram",
+		Wrapped: map[string]string{
+			"field":    field,
+			"interval": interval,
+		},
+	}
+}